@@ -19,6 +19,9 @@ const (
 	flagExtDirShort   Flag = "xd"
 	flagGalleryHost   Flag = "gallery-host"
 	flagGalleryScheme Flag = "gallery-scheme"
+	flagGallery       Flag = "gallery"
+	flagEditor        Flag = "editor"
+	flagPortableDir   Flag = "portable-dir"
 	flagOS            Flag = "os"
 	flagArch          Flag = "arch"
 	flagArchShort     Flag = "a"
@@ -26,6 +29,40 @@ const (
 	flagOutputShort   Flag = "o"
 	flagDebug         Flag = "debug"
 	flagDebugShort    Flag = "d"
+	flagOutputFormat  Flag = "output-format"
+	flagJSON          Flag = "json"
+	flagSave          Flag = "save"
+	flagShowOrigin    Flag = "show-origin"
+	flagTimeout       Flag = "timeout"
+	flagJobs          Flag = "jobs"
+	flagJobsShort     Flag = "j"
+	flagSHA256        Flag = "sha256"
+	flagChecksums     Flag = "checksums"
+	flagVerify        Flag = "verify"
+	flagNameTemplate  Flag = "name-template"
+	flagEngine        Flag = "engine"
+	flagPreRelease    Flag = "pre-release"
+	flagType          Flag = "type"
+	flagFrom          Flag = "from"
+	flagTo            Flag = "to"
+	flagChangelog     Flag = "changelog"
+	flagInstalled     Flag = "installed"
+	flagLockfile      Flag = "lockfile"
+	flagSBOMFormat    Flag = "format"
+	flagNPM           Flag = "npm"
+	flagDryRun        Flag = "dry-run"
+	flagToken         Flag = "token"
+
+	// Query flags
+	flagCategory  Flag = "category"
+	flagTag       Flag = "tag"
+	flagPublisher Flag = "publisher"
+	flagName      Flag = "name"
+	flagPlatform  Flag = "platform"
+	flagSort      Flag = "sort"
+	flagSortOrder Flag = "sort-order"
+	flagLimit     Flag = "limit"
+	flagPageSize  Flag = "page-size"
 )
 
 func ParseArgs() *Args {
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/echo"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+var (
+	ErrAssetType = fmt.Errorf("unknown asset type")
+)
+
+// assetTypes maps the `--type` values of `vsx asset` to gallery asset types
+var assetTypes = map[string]gallery.AssetType{
+	"manifest":   gallery.AssetManifest,
+	"readme":     gallery.AssetDetails,
+	"changelog":  gallery.AssetChangelog,
+	"license":    gallery.AssetLicense,
+	"icon":       gallery.AssetIcon,
+	"icon-small": gallery.AssetIconSmall,
+}
+
+// ParseAssetType produces the gallery asset type requested with `--type`,
+// either one of assetTypes or a full asset type (ex:
+// `Microsoft.VisualStudio.Services.Content.Details`)
+func ParseAssetType(cmd argv.Command) (gallery.AssetType, error) {
+	v, ok := cmd.Flag(flagType)
+	if !ok {
+		return "", fmt.Errorf("--%s is required", flagType)
+	}
+	if typ, ok := assetTypes[strings.ToLower(v[0])]; ok {
+		return typ, nil
+	}
+	if strings.HasPrefix(v[0], "Microsoft.") {
+		return v[0], nil
+	}
+	return "", fmt.Errorf(
+		"--%s: %w [%s], expected one of: %s",
+		flagType, ErrAssetType, v[0], strings.Join(slices.Sorted(maps.Keys(assetTypes)), ", "),
+	)
+}
+
+// FetchAsset writes an asset (ex: the changelog) of the single extension in
+// `cmd` to `--output`, or stdout
+func FetchAsset(ctx context.Context, g *Galleries, cfg *Config, cmd argv.Command) error {
+	if len(cmd.Args) != 1 {
+		return UsageError("Expected exactly one extension.")
+	}
+	typ, err := ParseAssetType(cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+	resolve, err := ParseResolveOptions(cfg, cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+
+	input := cmd.Args[0]
+	pub, id, ver, err := ParseExtension(input)
+	if err != nil {
+		return fmt.Errorf("failed to parse extension input [%s]: %w", input, err)
+	}
+	ext, err := ResolveExtension(ctx, g, pub, id, ver, resolve)
+	if err != nil {
+		return err
+	}
+
+	echo.Debugf("Fetching asset [%s] of [%s].", typ, ext)
+	asset, err := g.GetAsset(ctx, ext.Publisher, ext.Name, ext.Version, ext.TargetPlatform, typ)
+	if err != nil {
+		return fmt.Errorf("failed to fetch asset [%s] of [%s]: %w", typ, ext, err)
+	}
+
+	if v, ok := cmd.Flag(flagOutput, flagOutputShort); ok {
+		_, err := writeFile(ctx, v[0], bytes.NewReader(asset.Data))
+		return err
+	}
+
+	// Spare terminals from binary assets (ex: icons)
+	if isTerminal(os.Stdout) && !strings.HasPrefix(asset.ContentType, "text/") && !isText(asset.Data) {
+		return UsageError("Asset [%s] is binary, provide --%s to write it to a file.", typ, flagOutput)
+	}
+	_, err = os.Stdout.Write(asset.Data)
+	return err
+}
+
+// isText reports whether `data` appears to be text, being valid UTF-8 without
+// NUL bytes
+func isText(data []byte) bool {
+	return bytes.IndexByte(data, 0) == -1 && utf8.Valid(data)
+}
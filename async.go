@@ -1,35 +1,86 @@
 package main
 
 import (
-	"sync/atomic"
-	"time"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
 )
 
-// goLimit allows for simplified concurrent task distribution and await.
-//
-// Provided input `maxJobs` sets the ceiling on the number of concurrent tasks.
-//
-// The first closure returned (`spawn`) allows submission of any closure, which
-// will spawn ('go') when the number of concurrent workers falls below
-// `maxJobs`.
+const (
+	// defaultJobs is the default number of concurrent jobs
+	defaultJobs = 5
+)
+
+var (
+	ErrPanic = fmt.Errorf("job panicked")
+)
+
+// Pool runs jobs concurrently, with at most a fixed number running at a time.
 //
-// The second closure (`wait`) blocks ('wait's) until all active jobs have
-// completed.
-func goLimit(maxJobs int32) (spawn func(fn func()), wait func()) {
-	jobs := new(atomic.Int32)
-
-	return func(fn func()) {
-			for jobs.Load() >= maxJobs {
-				<-time.After(1 * time.Millisecond)
-			}
-			jobs.Add(1)
-			go func() {
-				defer jobs.Add(-1)
-				fn()
-			}()
-		}, func() {
-			for jobs.Load() > 0 {
-				<-time.After(1 * time.Millisecond)
+// Jobs submitted once the pool's context is canceled are never run, failing
+// with the context's error instead. A job which panics fails with ErrPanic
+// rather than crashing the process.
+type Pool struct {
+	ctx  context.Context
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewPool produces a pool running at most `size` jobs at a time (minimum 1),
+// each receiving `ctx`
+func NewPool(ctx context.Context, size int) *Pool {
+	return &Pool{
+		ctx: ctx,
+		sem: make(chan struct{}, max(size, 1)),
+	}
+}
+
+// Go submits `fn`, blocking until a worker is free or the pool's context is
+// canceled
+func (self *Pool) Go(fn func(ctx context.Context) error) {
+	self.mu.Lock()
+	i := len(self.errs)
+	self.errs = append(self.errs, nil)
+	self.mu.Unlock()
+
+	select {
+	case <-self.ctx.Done():
+		self.fail(i, self.ctx.Err())
+		return
+	case self.sem <- struct{}{}:
+	}
+
+	self.wg.Add(1)
+	go func() {
+		defer self.wg.Done()
+		defer func() { <-self.sem }()
+		defer func() {
+			if r := recover(); r != nil {
+				self.fail(i, fmt.Errorf("%w: %v\n%s", ErrPanic, r, debug.Stack()))
 			}
+		}()
+
+		if err := fn(self.ctx); err != nil {
+			self.fail(i, err)
 		}
+	}()
+}
+
+// Wait blocks until every submitted job has completed, producing each job's
+// error in order of submission
+func (self *Pool) Wait() []error {
+	self.wg.Wait()
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.errs
+}
+
+func (self *Pool) fail(i int, err error) {
+	self.mu.Lock()
+	self.errs[i] = err
+	self.mu.Unlock()
 }
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/illbjorn/argv"
+)
+
+// Audit risk levels, by score
+const (
+	riskLow    = "low"
+	riskMedium = "medium"
+	riskHigh   = "high"
+
+	riskMediumScore = 20
+	riskHighScore   = 50
+)
+
+// Audit check weights
+const (
+	weightActivationAll     = 30
+	weightActivationStartup = 15
+	weightNative            = 25
+	weightExecutables       = 10
+	weightLifecycleScripts  = 20
+	weightTerminalProfiles  = 15
+	weightTaskDefinitions   = 10
+	weightDebuggers         = 10
+	weightWorkspaceKind     = 5
+	weightNetwork           = 10
+)
+
+var (
+	ErrUnpinned = fmt.Errorf("expected an exact version")
+)
+
+var (
+	// lifecycleScripts are the npm scripts run on install or uninstall
+	lifecycleScripts = []string{"preinstall", "install", "postinstall", "vscode:uninstall"}
+
+	// executableExts are the extensions of scripts the system executes
+	executableExts = []string{".sh", ".bash", ".zsh", ".ps1", ".bat", ".cmd", ".exe", ".com", ".vbs"}
+
+	// networkDependencies are npm packages for network access beyond Node's
+	// built-in modules
+	networkDependencies = []string{
+		"axios",
+		"node-fetch",
+		"got",
+		"request",
+		"superagent",
+		"needle",
+		"undici",
+		"ws",
+		"socket.io-client",
+		"http-proxy-agent",
+		"https-proxy-agent",
+		"socks-proxy-agent",
+		"@grpc/grpc-js",
+		"ssh2",
+		"ftp",
+		"nodemailer",
+	}
+)
+
+// AuditReport is the capability risk assessment of a single extension
+type AuditReport struct {
+	Extension string         `json:"extension"`
+	Version   string         `json:"version"`
+	Score     int            `json:"score"`
+	Risk      string         `json:"risk"`
+	Findings  []AuditFinding `json:"findings"`
+}
+
+// AuditFinding is a single capability contributing to an extension's risk
+type AuditFinding struct {
+	Check  string `json:"check"`
+	Detail string `json:"detail"`
+	Weight int    `json:"weight"`
+}
+
+func (AuditReport) Columns() []string {
+	return []string{"Extension", "Version", "Score", "Risk", "Findings"}
+}
+
+func (self AuditReport) Row() []string {
+	findings := make([]string, len(self.Findings))
+	for i, finding := range self.Findings {
+		findings[i] = finding.Check
+	}
+	return []string{
+		self.Extension,
+		self.Version,
+		strconv.Itoa(self.Score),
+		self.Risk,
+		strings.Join(findings, ", "),
+	}
+}
+
+// AuditCommand reports the capability risk of each extension (or `.vsix`
+// file) in `cmd`, of every installed extension with `--installed` and of each
+// of the project's declared extensions with `--lockfile`
+func AuditCommand(ctx context.Context, g *Galleries, cfg *Config, format Format, cmd argv.Command) error {
+	inputs, installed, err := packageInputs(cfg, cmd)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 && !installed {
+		return UsageError("No extensions received (or --%s, --%s).", flagInstalled, flagLockfile)
+	}
+
+	pkgs, errs := loadPackages(ctx, g, cfg, cmd, inputs, installed)
+	reports := make([]AuditReport, len(pkgs))
+	for i, pkg := range pkgs {
+		reports[i] = AuditPackage(pkg)
+	}
+
+	return errors.Join(Render(os.Stdout, format, reports), errors.Join(errs...))
+}
+
+// packageInputs produces the extension (or `.vsix` file) inputs of `cmd`,
+// followed by the project's declared extensions with `--lockfile`, and whether
+// installed extensions are included (`--installed`)
+//
+// Declared extensions act as a lock file, each pinned to an exact version.
+func packageInputs(cfg *Config, cmd argv.Command) ([]string, bool, error) {
+	_, installed := cmd.Flag(flagInstalled)
+	_, lockfile := cmd.Flag(flagLockfile)
+	inputs := cmd.Args
+	if lockfile {
+		if len(cfg.Extensions) == 0 {
+			return nil, false, UsageError("--%s requires a project declaring extensions.", flagLockfile)
+		}
+		var errs []error
+		for _, input := range cfg.Extensions {
+			_, _, ver, err := ParseExtension(input)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("[%s]: %w", input, err))
+				continue
+			}
+			if spec, err := ParseVersionSpec(ver); err != nil || spec.exact == "" {
+				errs = append(errs, fmt.Errorf("%w for [%s] with --%s (ex: `%s@1.0.0`)", ErrUnpinned, input, flagLockfile, input))
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, false, err
+		}
+		inputs = append(slices.Clip(inputs), cfg.Extensions...)
+	}
+	return inputs, installed, nil
+}
+
+// loadPackages reads the package of each extension (or `.vsix` file) in
+// `inputs`, resolved as configured by `cmd`, and of every installed extension
+// if `installed` is set
+func loadPackages(
+	ctx context.Context,
+	g *Galleries,
+	cfg *Config,
+	cmd argv.Command,
+	inputs []string,
+	installed bool,
+) ([]*VSIXPackage, []error) {
+	var pkgs []*VSIXPackage
+	var errs []error
+
+	if installed {
+		extDirs, err := ExtensionDirs(cfg)
+		if err != nil {
+			return nil, []error{err}
+		}
+		exts, err := installedIn(extDirs)
+		if err != nil {
+			return nil, []error{err}
+		}
+		for _, ext := range exts {
+			pkg, err := readInstalledPackage(ext)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	if len(inputs) > 0 {
+		resolve, err := ParseResolveOptions(cfg, cmd)
+		if err != nil {
+			return nil, []error{UsageError("%s.", err)}
+		}
+		cache := NewPackageCache(cfg)
+		for _, input := range inputs {
+			pkg, err := loadPackage(ctx, g, cache, resolve, input)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	return pkgs, errs
+}
+
+// AuditPackage assesses the capabilities `pkg` declares and bundles
+func AuditPackage(pkg *VSIXPackage) AuditReport {
+	manifest := pkg.Manifest
+	self := AuditReport{
+		Extension: cmp.Or(pkg.Identity.Publisher, manifest.Publisher) + "." + cmp.Or(pkg.Identity.Name, manifest.Name),
+		Version:   cmp.Or(pkg.Identity.Version, manifest.Version),
+	}
+	add := func(check string, weight int, detail string) {
+		self.Findings = append(self.Findings, AuditFinding{Check: check, Detail: detail, Weight: weight})
+		self.Score += weight
+	}
+
+	// Activation
+	if slices.Contains(manifest.ActivationEvents, "*") {
+		add("activates_always", weightActivationAll, "activates on every editor start (`*`)")
+	} else if slices.Contains(manifest.ActivationEvents, "onStartupFinished") {
+		add("activates_on_startup", weightActivationStartup, "activates once the editor has started (`onStartupFinished`)")
+	}
+
+	// Bundled executables
+	var native, executables []string
+	for _, f := range pkg.Files {
+		name := strings.TrimPrefix(f.Name, "extension/")
+		switch {
+		case f.Native != "":
+			native = append(native, name+" ("+f.Native+")")
+		case slices.Contains(executableExts, strings.ToLower(path.Ext(name))):
+			executables = append(executables, name)
+		}
+	}
+	if len(native) > 0 {
+		add("native_binaries", weightNative, strings.Join(native, ", "))
+	}
+	if len(executables) > 0 {
+		add("executables", weightExecutables, strings.Join(executables, ", "))
+	}
+
+	// Scripts run by npm and the editor
+	var scripts []string
+	for _, script := range lifecycleScripts {
+		if cmd, ok := manifest.Scripts[script]; ok {
+			scripts = append(scripts, script+": "+cmd)
+		}
+	}
+	if len(scripts) > 0 {
+		add("lifecycle_scripts", weightLifecycleScripts, strings.Join(scripts, "; "))
+	}
+
+	// Contributions running processes
+	if profiles := terminalProfiles(manifest); len(profiles) > 0 {
+		add("terminal_profiles", weightTerminalProfiles, strings.Join(profiles, ", "))
+	}
+	if tasks := manifest.Contributions(contributionKey{"taskDefinitions", "type"}); len(tasks) > 0 {
+		add("task_providers", weightTaskDefinitions, strings.Join(tasks, ", "))
+	}
+	if debuggers := manifest.Contributions(contribDebuggers); len(debuggers) > 0 {
+		add("debuggers", weightDebuggers, strings.Join(debuggers, ", "))
+	}
+
+	// Running alongside the workspace means running on remote hosts
+	if slices.Contains(manifest.ExtensionKind, "workspace") {
+		add("workspace_kind", weightWorkspaceKind, "runs alongside the workspace (`extensionKind: workspace`)")
+	}
+
+	// Network access
+	var network []string
+	for _, dep := range slices.Sorted(maps.Keys(manifest.Dependencies)) {
+		if slices.Contains(networkDependencies, dep) {
+			network = append(network, dep)
+		}
+	}
+	if len(network) > 0 {
+		add("network_dependencies", weightNetwork, strings.Join(network, ", "))
+	}
+
+	switch {
+	case self.Score >= riskHighScore:
+		self.Risk = riskHigh
+	case self.Score >= riskMediumScore:
+		self.Risk = riskMedium
+	default:
+		self.Risk = riskLow
+	}
+
+	return self
+}
+
+// terminalProfiles produces the IDs of the terminal profiles `manifest`
+// contributes (`contributes.terminal.profiles`)
+func terminalProfiles(manifest ExtensionManifest) []string {
+	raw, ok := manifest.Contributes["terminal"]
+	if !ok {
+		return nil
+	}
+	var terminal struct {
+		Profiles []struct {
+			ID string `json:"id"`
+		} `json:"profiles"`
+	}
+	if err := json.Unmarshal(raw, &terminal); err != nil {
+		return nil
+	}
+
+	var ids []string
+	for _, profile := range terminal.Profiles {
+		ids = append(ids, profile.ID)
+	}
+	return ids
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/zest"
+)
+
+func TestAuditPackage(t *testing.T) {
+	z := zest.New(t)
+
+	low := AuditPackage(testPackage(t, `{
+	  "activationEvents": ["onLanguage:go"],
+	  "contributes": {"commands": [{"command": "tools.run"}]}
+	}`, map[string]string{
+		"extension/dist/extension.js": "console.log(1)",
+	}))
+	z.Assert(low.Score == 0, "expected a score of 0, got %d", low.Score)
+	z.Assert(low.Risk == riskLow, "expected risk [%s], got [%s]", riskLow, low.Risk)
+
+	high := AuditPackage(testPackage(t, `{
+	  "activationEvents": ["*", "onStartupFinished"],
+	  "extensionKind": "workspace",
+	  "scripts": {"postinstall": "node setup.js", "build": "tsc"},
+	  "contributes": {
+	    "terminal": {"profiles": [{"id": "tools.shell", "title": "Tools"}]},
+	    "taskDefinitions": [{"type": "tools"}],
+	    "debuggers": [{"type": "tools-debug"}]
+	  },
+	  "dependencies": {"axios": "1.0.0", "lodash": "4.0.0"}
+	}`, map[string]string{
+		"extension/bin/tool":       "\x7fELF" + strings.Repeat("\x00", 60),
+		"extension/scripts/run.sh": "#!/bin/sh",
+	}))
+	var got []string
+	for _, finding := range high.Findings {
+		got = append(got, finding.Check+": "+finding.Detail)
+	}
+	want := []string{
+		"activates_always: activates on every editor start (`*`)",
+		"native_binaries: bin/tool (ELF)",
+		"executables: scripts/run.sh",
+		"lifecycle_scripts: postinstall: node setup.js",
+		"terminal_profiles: tools.shell",
+		"task_providers: tools",
+		"debuggers: tools-debug",
+		"workspace_kind: runs alongside the workspace (`extensionKind: workspace`)",
+		"network_dependencies: axios",
+	}
+	z.Assert(slices.Equal(got, want), "expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	z.Assert(high.Score == 135, "expected a score of 135, got %d", high.Score)
+	z.Assert(high.Risk == riskHigh, "expected risk [%s], got [%s]", riskHigh, high.Risk)
+}
+
+func TestPackageInputs(t *testing.T) {
+	z := zest.New(t)
+
+	cmd, err := argv.Parse([]string{cmdLicenses, "ms-python.python@2024.1.0", "--" + flagLockfile, "--" + flagInstalled})
+	z.Assert(err == nil, "failed to parse: %s", err)
+
+	// Declared extensions follow the inputs
+	cfg := &Config{Extensions: []string{"golang.go@0.45.0", "usernamehw.errorlens@3.26.0"}}
+	inputs, installed, err := packageInputs(cfg, cmd)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(installed, "expected installed extensions included")
+	want := []string{"ms-python.python@2024.1.0", "golang.go@0.45.0", "usernamehw.errorlens@3.26.0"}
+	z.Assert(slices.Equal(inputs, want), "expected %q, got %q", want, inputs)
+
+	// Every declared extension must be pinned
+	for _, declared := range []string{"golang.go", "golang.go@latest", "golang.go@^0.45"} {
+		cfg := &Config{Extensions: []string{"usernamehw.errorlens@3.26.0", declared}}
+		_, _, err := packageInputs(cfg, cmd)
+		z.Assert(errors.Is(err, ErrUnpinned), "[%s]: expected [%s], got [%v]", declared, ErrUnpinned, err)
+	}
+}
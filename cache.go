@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/illbjorn/echo"
+)
+
+const (
+	// cachePackagesDir is the cache subdirectory holding VSIX packages
+	cachePackagesDir = "packages"
+)
+
+// PackageCache holds previously fetched VSIX packages, keyed by gallery and
+// resolved extension version and platform
+//
+// Published versions are immutable, so cached packages never expire. Separate
+// galleries may publish different packages under the same version, so a
+// package is only ever served for the gallery it was fetched from.
+//
+// Packages are cached at `<dir>/<gallery host>/<DirName>.vsix`.
+type PackageCache struct {
+	// dir is the cache directory, caching is disabled if empty
+	dir string
+}
+
+// NewPackageCache produces the package cache of `cfg`
+func NewPackageCache(cfg *Config) PackageCache {
+	if cfg.CacheDir == "" {
+		return PackageCache{}
+	}
+	return PackageCache{dir: filepath.Join(cfg.CacheDir, cachePackagesDir)}
+}
+
+// path produces the path of `ext` in the cache, false if it has none: the
+// cache is disabled, the gallery unknown or the names escape the cache
+func (self PackageCache) path(ext ResolvedExtension) (string, bool) {
+	if self.dir == "" || ext.Gallery == "" {
+		return "", false
+	}
+
+	// Ports are separated by `:`, which Windows forbids in file names
+	gallery := strings.ReplaceAll(ext.Gallery, ":", "_")
+	name := filepath.Join(gallery, ext.DirName()+".vsix")
+	if !filepath.IsLocal(name) {
+		return "", false
+	}
+	return filepath.Join(self.dir, name), true
+}
+
+// Get produces the cached package of `ext`, if any
+//
+// Extensions of no known gallery are never cached.
+func (self PackageCache) Get(ext ResolvedExtension) ([]byte, bool) {
+	path, ok := self.path(ext)
+	if !ok {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put caches package `data` of `ext`
+func (self PackageCache) Put(ctx context.Context, ext ResolvedExtension, data []byte) error {
+	path, ok := self.path(ext)
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), fileModeRWX); err != nil {
+		return fmt.Errorf("failed to create cache directory [%s]: %w", filepath.Dir(path), err)
+	}
+	_, err := writeFile(ctx, path, bytes.NewReader(data))
+	return err
+}
+
+// fetchPackage produces the VSIX package of `ext`, from `cache` if present and
+// otherwise from `g`, caching it
+func fetchPackage(ctx context.Context, g *Galleries, cache PackageCache, ext ResolvedExtension) ([]byte, error) {
+	if data, ok := cache.Get(ext); ok {
+		echo.Debugf("Using cached package of [%s].", ext)
+		return data, nil
+	}
+
+	pkg, err := g.GetResolvedExtension(ctx, ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch [%s]: %w", ext, err)
+	}
+
+	// Verify what the gallery reported before trusting it to the cache
+	if _, err := verifyPackage(pkg); err != nil {
+		return nil, fmt.Errorf("[%s]: %w", ext, err)
+	}
+	data, err := io.ReadAll(io.NewSectionReader(pkg, 0, pkg.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read [%s]: %w", ext, err)
+	}
+
+	if err := cache.Put(ctx, ext, data); err != nil {
+		echo.Debugf("Failed to cache [%s]: %s.", ext, err)
+	}
+	return data, nil
+}
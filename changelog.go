@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/echo"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+// ChangelogSection is the section of an extension's changelog describing a
+// single version
+type ChangelogSection struct {
+	Extension string `json:"extension"`
+	Version   string `json:"version"`
+
+	// Heading is the section's heading line, as written (ex: `## [1.2.0] -
+	// 2024-05-01`)
+	Heading string `json:"heading"`
+
+	// Body is the section's content, excluding its heading
+	Body string `json:"body"`
+}
+
+func (ChangelogSection) Columns() []string {
+	return []string{"Extension", "Version", "Changes"}
+}
+
+func (self ChangelogSection) Row() []string {
+	return []string{self.Extension, self.Version, self.Body}
+}
+
+// ChangelogCommand prints the changelog sections of each extension in `cmd`
+// between `--from` (default: the installed version) and `--to` (default: the
+// version resolved from the extension input)
+func ChangelogCommand(ctx context.Context, g *Galleries, cfg *Config, format Format, cmd argv.Command) error {
+	if len(cmd.Args) == 0 {
+		return UsageError("No extensions received.")
+	}
+	resolve, err := ParseResolveOptions(cfg, cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+	from, hasFrom := cmd.Flag(flagFrom)
+	to, hasTo := cmd.Flag(flagTo)
+	if hasTo && len(cmd.Args) > 1 {
+		return UsageError("--%s requires exactly one extension.", flagTo)
+	}
+
+	// Without `--from`, changes are shown since the installed version
+	var installed []InstalledExtension
+	if !hasFrom {
+		if extDirs, err := ExtensionDirs(cfg); err != nil {
+			echo.Debugf("Not comparing against installed versions: %s.", err)
+		} else if installed, err = installedIn(extDirs); err != nil {
+			return err
+		}
+	}
+
+	var sections []ChangelogSection
+	var errs []error
+	for _, input := range cmd.Args {
+		pub, id, ver, err := ParseExtension(input)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse extension input [%s]: %w", input, err))
+			continue
+		}
+		if hasTo {
+			ver = to[0]
+		}
+		ext, err := ResolveExtension(ctx, g, pub, id, ver, resolve)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		since := ""
+		if hasFrom {
+			since = from[0]
+		} else {
+			since = installedVersion(installed, ext.ID())
+		}
+
+		found, err := ExtensionChangelog(ctx, g, ext, since)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sections = append(sections, found...)
+	}
+
+	if format == FormatTable {
+		return errors.Join(printChangelog(os.Stdout, sections), errors.Join(errs...))
+	}
+	return errors.Join(Render(os.Stdout, format, sections), errors.Join(errs...))
+}
+
+// installedVersion produces the newest installed version of extension `id`, or
+// the empty string if not installed
+func installedVersion(installed []InstalledExtension, id string) string {
+	var version string
+	for _, ext := range installed {
+		if strings.EqualFold(ext.ID(), id) && compareVersions(ext.Version, version) > 0 {
+			version = ext.Version
+		}
+	}
+	return version
+}
+
+// ExtensionChangelog fetches the changelog of `ext`, producing the sections of
+// versions after `since` up to and including `ext`'s version
+//
+// Every section up to `ext`'s version is produced if `since` is empty.
+func ExtensionChangelog(ctx context.Context, g *Galleries, ext ResolvedExtension, since string) ([]ChangelogSection, error) {
+	asset, err := g.GetAsset(ctx, ext.Publisher, ext.Name, ext.Version, ext.TargetPlatform, gallery.AssetChangelog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the changelog of [%s]: %w", ext, err)
+	}
+
+	parsed, err := parseChangelog(string(asset.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the changelog of [%s]: %w", ext, err)
+	}
+
+	var sections []ChangelogSection
+	for _, section := range parsed {
+		if since != "" && compareVersions(section.Version, since) <= 0 {
+			continue
+		}
+		if compareVersions(section.Version, ext.Version) > 0 {
+			continue
+		}
+		section.Extension = ext.ID()
+		sections = append(sections, section)
+	}
+
+	return sections, nil
+}
+
+// changelogVersion matches the version in a changelog heading (ex: `## [1.2.0]
+// - 2024-05-01`, `# v2.0.0-beta.1`)
+var changelogVersion = regexp.MustCompile(`\bv?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.-]+)?)\b`)
+
+// parseChangelog splits markdown changelog `text` into one section per
+// versioned heading
+//
+// The level of the first heading naming a version is taken as that of every
+// version's heading, deeper headings (ex: `### Fixed`) belonging to the
+// section body. Headings of that level naming no version (ex: `##
+// Unreleased`) end the previous section without starting one, as does
+// anything before the first version.
+func parseChangelog(text string) ([]ChangelogSection, error) {
+	var (
+		sections []ChangelogSection
+		current  *ChangelogSection
+		body     strings.Builder
+		level    int
+		fenced   bool
+	)
+	flush := func() {
+		if current != nil {
+			current.Body = strings.TrimSpace(body.String())
+			sections = append(sections, *current)
+		}
+		current = nil
+		body.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Headings within code blocks aren't headings
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		depth := len(line) - len(strings.TrimLeft(line, "#"))
+		if fenced || depth == 0 || (level != 0 && depth > level) {
+			if current != nil {
+				body.WriteString(line + "\n")
+			}
+			continue
+		}
+
+		heading := strings.TrimSpace(line[depth:])
+		match := changelogVersion.FindStringSubmatch(heading)
+		if match == nil {
+			flush()
+			continue
+		}
+
+		if level == 0 {
+			level = depth
+		}
+		flush()
+		current = &ChangelogSection{Version: match[1], Heading: strings.TrimSpace(line)}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return sections, nil
+}
+
+// printChangelog writes `sections` to `w` as markdown, headed by the extension
+// each belongs to
+func printChangelog(w io.Writer, sections []ChangelogSection) error {
+	var b strings.Builder
+	for i, section := range sections {
+		if i == 0 || sections[i-1].Extension != section.Extension {
+			fmt.Fprintf(&b, ">> %s\n\n", section.Extension)
+		}
+		b.WriteString(section.Heading + "\n")
+		if section.Body != "" {
+			b.WriteString("\n" + section.Body + "\n")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+const testChangelog = "# Change Log\n\nAll notable changes.\n\n" +
+	"## [Unreleased]\n\n- Upcoming\n\n" +
+	"## [1.5.0] - 2024-06-01\n\n### Added\n\n- Feature\n\n```md\n## 9.9.9\n```\n\n" +
+	"## v1.4.2\n\n- Fix\n\n" +
+	"## 1.2.0\n\n- Initial\n"
+
+func TestParseChangelog(t *testing.T) {
+	z := zest.New(t)
+
+	sections, err := parseChangelog(testChangelog)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(len(sections) == 3, "expected 3 sections, got %d", len(sections))
+
+	want := []string{"1.5.0", "1.4.2", "1.2.0"}
+	for i, section := range sections {
+		z.Assert(section.Version == want[i], "expected version [%s], got [%s]", want[i], section.Version)
+	}
+	z.Assert(sections[0].Heading == "## [1.5.0] - 2024-06-01", "unexpected heading [%s]", sections[0].Heading)
+	z.Assert(
+		sections[0].Body == "### Added\n\n- Feature\n\n```md\n## 9.9.9\n```",
+		"unexpected body [%s]", sections[0].Body,
+	)
+	z.Assert(sections[2].Body == "- Initial", "unexpected body [%s]", sections[2].Body)
+
+	// Lines beyond the scanner's buffer fail rather than truncate the changelog
+	_, err = parseChangelog(testChangelog + strings.Repeat("x", 1<<20) + "\n")
+	z.Assert(errors.Is(err, bufio.ErrTooLong), "expected [%s], got [%v]", bufio.ErrTooLong, err)
+}
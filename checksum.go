@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	// checksumFileName is the name of the checksum manifest written alongside
+	// downloads, in the format of `sha256sum`
+	checksumFileName = "SHA256SUMS"
+)
+
+var (
+	ErrChecksum       = fmt.Errorf("checksum mismatch")
+	ErrChecksumFormat = fmt.Errorf("invalid SHA-256 checksum, expected 64 hexadecimal characters")
+	ErrChecksumCount  = fmt.Errorf("expected one --sha256 value per extension")
+	ErrChecksumFile   = fmt.Errorf("failed to read checksum file")
+)
+
+// Digests are the hex-encoded digests of a file
+type Digests struct {
+	SHA256 string
+	SHA512 string
+}
+
+// Verify compares the SHA-256 digest against hex-encoded digest `want`
+func (self Digests) Verify(want string) error {
+	if !strings.EqualFold(self.SHA256, want) {
+		return fmt.Errorf("%w: expected [%s], got [%s]", ErrChecksum, want, self.SHA256)
+	}
+	return nil
+}
+
+// digest computes the digests of the content of `r`
+func digest(r io.Reader) (Digests, error) {
+	h256, h512 := sha256.New(), sha512.New()
+	if _, err := io.Copy(io.MultiWriter(h256, h512), r); err != nil {
+		return Digests{}, fmt.Errorf("failed to compute checksums: %w", err)
+	}
+	return Digests{
+		SHA256: hex.EncodeToString(h256.Sum(nil)),
+		SHA512: hex.EncodeToString(h512.Sum(nil)),
+	}, nil
+}
+
+// verifyPackage digests VSIX package `pkg`, checking the digest against that
+// the gallery reported, if any
+//
+// Every package fetched from a gallery is checked here before it's used.
+func verifyPackage(pkg gallery.Package) (Digests, error) {
+	digests, err := digest(io.NewSectionReader(pkg, 0, pkg.Size()))
+	if err != nil {
+		return Digests{}, err
+	}
+	if pkg.SHA256 != "" {
+		if err := digests.Verify(pkg.SHA256); err != nil {
+			return Digests{}, fmt.Errorf("gallery-reported SHA-256: %w", err)
+		}
+	}
+	return digests, nil
+}
+
+// ParseSHA256 produces the expected SHA-256 digests provided with `--sha256`,
+// one for each of `n` extensions, or nil if none were provided
+func ParseSHA256(cmd argv.Command, n int) ([]string, error) {
+	v, ok := cmd.Flag(flagSHA256)
+	if !ok {
+		return nil, nil
+	}
+	if len(v) != n {
+		return nil, fmt.Errorf("--%s: %w (received %d for %d)", flagSHA256, ErrChecksumCount, len(v), n)
+	}
+	for _, sum := range v {
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("--%s [%s]: %w", flagSHA256, sum, ErrChecksumFormat)
+		}
+	}
+	return v, nil
+}
+
+// WriteChecksums adds the SHA-256 digest of each successful download in
+// `results` to the checksum manifest in `dir`, creating it if necessary
+//
+// Entries for files not among `results` are left as they are.
+func WriteChecksums(dir string, results []Result) error {
+	path := filepath.Join(dir, checksumFileName)
+	sums, err := readChecksums(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if sums == nil {
+		sums = make(map[string]string)
+	}
+
+	for _, result := range results {
+		if result.Status == statusSuccess && result.SHA256 != "" {
+			sums[filepath.Base(result.Path)] = result.SHA256
+		}
+	}
+
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(sums)) {
+		fmt.Fprintf(&b, "%s  %s\n", sums[name], name)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), fileModeRW); err != nil {
+		return fmt.Errorf("failed to write checksum file [%s]: %w", path, err)
+	}
+
+	return nil
+}
+
+// readChecksums reads the checksum manifest at `path`, mapping each file name
+// to its hex-encoded SHA-256 digest
+//
+// Both the text (`HASH  NAME`) and binary (`HASH *NAME`) forms are accepted.
+func readChecksums(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w [%s]: %w", ErrChecksumFile, path, err)
+	}
+	defer f.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sum, name, ok := strings.Cut(line, " ")
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%w [%s]: line %d: expected `HASH  NAME`", ErrChecksumFile, path, lineNo)
+		}
+		if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%w [%s]: line %d: %w", ErrChecksumFile, path, lineNo, ErrChecksumFormat)
+		}
+		sums[name] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w [%s]: %w", ErrChecksumFile, path, err)
+	}
+
+	return sums, nil
+}
+
+// VerifyChecksums rechecks every file listed in the checksum manifest at
+// `path`, relative to the manifest's directory
+func VerifyChecksums(ctx context.Context, path string) ([]Result, error) {
+	sums, err := readChecksums(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	names := slices.Sorted(maps.Keys(sums))
+	results := make([]Result, len(names))
+	errs := make([]error, len(names))
+	for i, name := range names {
+		results[i].Extension = name
+		results[i].Path = filepath.Join(dir, name)
+		errs[i] = verifyFile(ctx, &results[i], sums[name])
+	}
+
+	return collectResults(results, errs), errors.Join(errs...)
+}
+
+// verifyFile checks the file at `result.Path` against SHA-256 digest `want`,
+// recording its digests in `result`
+func verifyFile(ctx context.Context, result *Result, want string) error {
+	f, err := os.Open(result.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open [%s]: %w", result.Path, err)
+	}
+	defer f.Close()
+
+	digests, err := digest(contextReader{ctx, f})
+	if err != nil {
+		return err
+	}
+	result.SHA256, result.SHA512 = digests.SHA256, digests.SHA512
+
+	return digests.Verify(want)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"archive/zip"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/illbjorn/argv"
 	"github.com/illbjorn/echo"
@@ -20,14 +22,51 @@ type CMD = string
 
 const (
 	// CMDs
-	cmdQuery    CMD = "query"
-	cmdInstall  CMD = "install"
-	cmdDownload CMD = "download"
-	cmdList     CMD = "list"
-	cmdExit     CMD = "exit"
+	cmdQuery     CMD = "query"
+	cmdInstall   CMD = "install"
+	cmdDownload  CMD = "download"
+	cmdList      CMD = "list"
+	cmdInfo      CMD = "info"
+	cmdOutdated  CMD = "outdated"
+	cmdExit      CMD = "exit"
+	cmdShell     CMD = "shell"
+	cmdConfig    CMD = "config"
+	cmdEditors   CMD = "editors"
+	cmdAsset     CMD = "asset"
+	cmdChangelog CMD = "changelog"
+	cmdInspect   CMD = "inspect"
+	cmdDiff      CMD = "diff"
+	cmdPolicy    CMD = "policy"
+	cmdAudit     CMD = "audit"
+	cmdLicenses  CMD = "licenses"
+	cmdSBOM      CMD = "sbom"
+	cmdPublish   CMD = "publish"
 )
 
-func Run(g gallery.Gallery, cfg *Config, cmd argv.Command) error {
+var (
+	ErrTimeout = fmt.Errorf("invalid timeout, expected a positive duration (example: 2m)")
+	ErrJobs    = fmt.Errorf("invalid job count, expected a positive integer")
+)
+
+// Run executes `cmd`, which is abandoned once `ctx` is canceled or `--timeout`
+// elapses
+func Run(ctx context.Context, g *Galleries, cfg *Config, cmd argv.Command) error {
+	format, err := ParseFormat(cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+
+	// Bound the whole command by `--timeout`, if provided
+	if v, ok := cmd.Flag(flagTimeout); ok {
+		timeout, err := time.ParseDuration(v[0])
+		if err != nil || timeout <= 0 {
+			return UsageError("--%s [%s]: %s.", flagTimeout, v[0], ErrTimeout)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	switch cmd.Name {
 	case "":
 		return fmt.Errorf("received no command")
@@ -35,315 +74,805 @@ func Run(g gallery.Gallery, cfg *Config, cmd argv.Command) error {
 	default:
 		return fmt.Errorf("received unknown command [%s]", cmd)
 
+	case cmdConfig:
+		return ConfigCommand(cfg, format, cmd)
+
 	case cmdQuery:
-		return QueryExtensions(g, cmd)
+		return QueryExtensions(ctx, g, format, cmd)
+
+	case cmdInfo:
+		return ExtensionInfo(ctx, g, cfg, format, cmd)
+
+	case cmdEditors:
+		return ListEditors(cfg, format)
+
+	case cmdAsset:
+		return FetchAsset(ctx, g, cfg, cmd)
+
+	case cmdChangelog:
+		return ChangelogCommand(ctx, g, cfg, format, cmd)
+
+	case cmdInspect:
+		return InspectCommand(format, cmd)
+
+	case cmdDiff:
+		return DiffCommand(ctx, g, cfg, format, cmd)
+
+	case cmdPolicy:
+		return PolicyCommand(ctx, g, cfg, format, cmd)
+
+	case cmdAudit:
+		return AuditCommand(ctx, g, cfg, format, cmd)
+
+	case cmdLicenses:
+		return LicensesCommand(ctx, g, cfg, format, cmd)
+
+	case cmdSBOM:
+		return SBOMCommand(ctx, g, cfg, cmd)
+
+	case cmdPublish:
+		return PublishCommand(ctx, g, cmd)
+
+	case cmdList:
+		extDirs, err := ExtensionDirs(cfg)
+		if err != nil {
+			return err
+		}
+		return ListExtensions(extDirs, format)
+
+	case cmdOutdated:
+		jobs, err := ParseJobs(cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		resolve, err := ParseResolveOptions(cfg, cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		extDirs, err := ExtensionDirs(cfg)
+		if err != nil {
+			return err
+		}
+		_, changelog := cmd.Flag(flagChangelog)
+		return OutdatedExtensions(ctx, g, extDirs, format, OutdatedOptions{
+			Jobs:      jobs,
+			Changelog: changelog,
+			Resolve:   resolve,
+		})
 
 	case cmdInstall:
-		return InstallExtensions(g, cfg.ExtensionDir, cmd)
+		extDirs, err := ExtensionDirs(cfg)
+		if err != nil {
+			return err
+		}
+		jobs, err := ParseJobs(cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		resolve, err := ParseResolveOptions(cfg, cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		policy, err := LoadPolicy(cfg)
+		if err != nil {
+			return err
+		}
+		inputs := projectExtensions(cfg, cmd.Args)
+		results, err := InstallExtensions(ctx, g, extDirs, inputs, InstallOptions{
+			Jobs:    jobs,
+			Resolve: resolve,
+			Policy:  policy,
+		})
+		return errors.Join(err, Render(os.Stdout, format, results))
 
 	case cmdDownload:
+		// Recheck previous downloads rather than downloading, if asked to
+		if v, ok := cmd.Flag(flagVerify); ok {
+			results, err := VerifyChecksums(ctx, v[0])
+			return errors.Join(err, Render(os.Stdout, format, results))
+		}
+
+		jobs, err := ParseJobs(cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		inputs := projectExtensions(cfg, cmd.Args)
+		sums, err := ParseSHA256(cmd, len(inputs))
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		tmpl, err := ParseNameTemplate(cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		resolve, err := ParseResolveOptions(cfg, cmd)
+		if err != nil {
+			return UsageError("%s.", err)
+		}
+		policy, err := LoadPolicy(cfg)
+		if err != nil {
+			return err
+		}
+		_, checksums := cmd.Flag(flagChecksums)
+		opts := DownloadOptions{
+			Resolve:      resolve,
+			Policy:       policy,
+			NameTemplate: tmpl,
+			Jobs:         jobs,
+			Progress:     NewProgressReporter(os.Stderr, format),
+			SHA256:       sums,
+			Checksums:    checksums,
+		}
+
 		// Discern where to put the downloads
 		//
 		// We'll prefer the user-provided [--output, -o] flag but fall back to the
-		// current working directory if the flag was not provided
+		// current working directory if the flag was not provided. An output
+		// ending in `.vsix` names the file of a single extension.
 		flagOutputValues, ok := cmd.Flag(flagOutput, flagOutputShort)
-		var output string
-		if !ok {
-			var err error
-			output, err = os.Getwd()
+		switch {
+		case !ok:
+			opts.OutDir, err = os.Getwd()
 			if err != nil {
 				return fmt.Errorf(
 					"no download directory was specified and working directory retrieval failed: %w",
 					err,
 				)
 			}
-		} else {
-			output = flagOutputValues[0]
+		case strings.EqualFold(filepath.Ext(flagOutputValues[0]), ".vsix"):
+			if len(inputs) != 1 {
+				return UsageError("An output file name requires exactly one extension.")
+			}
+			if _, ok := cmd.Flag(flagNameTemplate); ok {
+				return UsageError("Received both an output file name and --%s.", flagNameTemplate)
+			}
+			opts.OutDir, opts.Name = filepath.Split(flagOutputValues[0])
+			if opts.OutDir == "" {
+				opts.OutDir = "."
+			}
+		default:
+			opts.OutDir = flagOutputValues[0]
 		}
 
-		return DownloadExtensions(g, output, cmd)
+		results, err := DownloadExtensions(ctx, g, inputs, opts)
+		return errors.Join(err, Render(os.Stdout, format, results))
 	}
 }
 
-func InstallExtensions(g gallery.Gallery, extDir string, cmd argv.Command) error {
-	// If we don't have an extension directory, try to locate one in the home
-	// directory
-	if extDir == "" {
-		var err error
-		extDir, err = ExtensionDir()
-		if err != nil {
-			return fmt.Errorf(
-				"received no VSCode extension directory and failed to locate one: %w",
-				err,
-			)
+// ParseJobs produces the number of concurrent jobs requested by `cmd`,
+// defaulting to defaultJobs
+func ParseJobs(cmd argv.Command) (int, error) {
+	v, ok := cmd.Flag(flagJobs, flagJobsShort)
+	if !ok {
+		return defaultJobs, nil
+	}
+	jobs, err := strconv.Atoi(v[0])
+	if err != nil || jobs < 1 {
+		return 0, fmt.Errorf("--%s [%s]: %w", flagJobs, v[0], ErrJobs)
+	}
+	return jobs, nil
+}
+
+// projectExtensions produces `args`, or the project's declared extension set
+// when no extensions were provided
+func projectExtensions(cfg *Config, args []string) []string {
+	if len(args) > 0 || len(cfg.Extensions) == 0 {
+		return args
+	}
+	echo.Infof("Using the [%d] extension(s) declared by [%s].", len(cfg.Extensions), cfg.project)
+	return cfg.Extensions
+}
+
+// Result is the outcome of an install or download of a single extension
+type Result struct {
+	Extension string `json:"extension"`
+	Version   string `json:"version"`
+	Status    string `json:"status"`
+	Path      string `json:"path,omitempty"`
+	SHA256    string `json:"sha256,omitempty"`
+	SHA512    string `json:"sha512,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+const (
+	statusSuccess = "success"
+	statusFailed  = "failed"
+)
+
+func (Result) Columns() []string {
+	return []string{"Extension", "Version", "Status", "Detail"}
+}
+
+func (self Result) Row() []string {
+	detail := self.Path
+	if self.Error != "" {
+		detail = self.Error
+	}
+	return []string{self.Extension, self.Version, self.Status, detail}
+}
+
+// collectResults finalizes each result's status from its corresponding entry in
+// `errs`
+func collectResults(results []Result, errs []error) []Result {
+	for i := range results {
+		if errs[i] != nil {
+			results[i].Status = statusFailed
+			results[i].Error = errs[i].Error()
+		} else {
+			results[i].Status = statusSuccess
 		}
 	}
+	return results
+}
+
+// InstallOptions configures InstallExtensions
+type InstallOptions struct {
+	// Jobs is the number of installs run at a time
+	Jobs int
 
-	spawn, wait := goLimit(5)
+	// Resolve constrains the versions installed
+	Resolve ResolveOptions
+
+	// Policy decides which extensions may be installed, if set
+	Policy *Policy
+}
+
+// InstallExtensions installs each of `inputs` to every one of `extDirs`, as
+// configured by `opts`
+func InstallExtensions(
+	ctx context.Context,
+	g *Galleries,
+	extDirs []string,
+	inputs []string,
+	opts InstallOptions,
+) ([]Result, error) {
+	pool := NewPool(ctx, opts.Jobs)
 
 	// Process all requested extensions
-	var errs = make([]error, len(cmd.Args))
-	for i, input := range cmd.Args {
-		spawn(func() {
+	//
+	// Each extension is fetched once and installed to every target extension
+	// directory, producing a result per extension per target.
+	var errs = make([]error, len(inputs)*len(extDirs))
+	var results = make([]Result, len(inputs)*len(extDirs))
+	for i, input := range inputs {
+		targets := results[i*len(extDirs) : (i+1)*len(extDirs)]
+		targetErrs := errs[i*len(extDirs) : (i+1)*len(extDirs)]
+		for t := range targets {
+			targets[t].Extension = input
+		}
+
+		// Failures preceding the unzip (returned) apply to every target, those
+		// of the unzip itself to their target alone
+		pool.Go(func(ctx context.Context) error {
 			// Parse the extension input
 			pub, id, ver, err := ParseExtension(input)
 			if err != nil {
-				errs[i] = fmt.Errorf(
+				return fmt.Errorf(
 					"failed to parse extension input[%s]: %s",
 					input, err,
 				)
-				return
-			}
-			// If we got no `ver` value, use the default ('latest')
-			if ver == "" {
-				ver = "latest"
 			}
 
-			// Assemble the full output directory name
-			extDirName := fmt.Sprintf("%s.%s-%s", pub, id, ver)
-			extDir = filepath.Join(extDir, extDirName)
+			// Resolve the version to install
+			ext, err := ResolveExtension(ctx, g, pub, id, ver, opts.Resolve)
+			if err != nil {
+				return err
+			}
+			if !filepath.IsLocal(ext.DirName()) {
+				return fmt.Errorf("%w [%s]", ErrUnsafeName, ext.DirName())
+			}
+			for t, extDir := range extDirs {
+				targets[t].Version = ext.Version
+				targets[t].Path = filepath.Join(extDir, ext.DirName())
+			}
 
 			// Get the `.vsix` file stream
-			stream, err := g.GetExtension(context.Background(), pub, id, ver)
+			stream, err := g.GetResolvedExtension(ctx, ext)
 			if err != nil {
-				errs[i] = fmt.Errorf("failed to fetch gallery extension: %w", err)
-				return
+				return fmt.Errorf("failed to fetch gallery extension: %w", err)
+			}
+			if _, err := verifyPackage(stream); err != nil {
+				return fmt.Errorf("[%s]: %w", ext, err)
 			}
 
 			// Init the zip reader
 			zr, err := zip.NewReader(stream, stream.Size())
 			if err != nil {
-				errs[i] = fmt.Errorf("failed to init zip reader: %w", err)
-				return
+				return fmt.Errorf("failed to init zip reader: %w", err)
 			}
 
-			// Unzip all files
-			for _, zipFile := range zr.File {
-				// Ignore non-`extension`-directory files
-				if !strings.HasPrefix(zipFile.Name, "extension") {
-					echo.Debugf("Skipping file [%s].", zipFile.Name)
-					continue
-				}
+			// Nothing is installed against policy
+			if err := opts.Policy.CheckPackage(ext, zr); err != nil {
+				return err
+			}
 
-				// Slice off the `extension` prefix
-				i := strings.IndexByte(zipFile.Name, '/')
-				if i == -1 || i == len(zipFile.Name)-1 {
-					echo.Debugf("Skipping zipped file [%s](no path suffix).", zipFile.Name)
+			// Unzip to each target
+			for t := range targets {
+				if err := installExtension(ctx, zr, targets[t].Path); err != nil {
+					targetErrs[t] = err
 					continue
 				}
-				name := zipFile.Name[i+1:]
 
-				// Define the output path
-				output := filepath.Join(extDir, name)
-				echo.Debugf("Outputting file [%s] to [%s].", name, output)
+				echo.Infof("[%s] install complete to [%s].", ext, targets[t].Path)
+			}
 
-				// Create any requisite directories
-				err = os.MkdirAll(filepath.Dir(output), fileModeRWX)
-				if err != nil {
-					errs[i] = fmt.Errorf(
-						"failed to create output directory structure: %w",
-						err,
-					)
-					return
-				}
+			return nil
+		})
+	}
 
-				// Get a readable stream to the zipped file
-				src, err := zr.Open(zipFile.Name)
-				if err != nil {
-					errs[i] = fmt.Errorf(
-						"failed to read zipped file[%s]: %w",
-						zipFile.Name, err,
-					)
-					return
-				}
+	// Wait for all workers to complete
+	for i, err := range pool.Wait() {
+		if err == nil {
+			continue
+		}
+		for t := range extDirs {
+			errs[i*len(extDirs)+t] = err
+		}
+	}
 
-				// Get a writable stream to the on-disk file
-				dst, err := os.OpenFile(output, fileFlagsOverwrite, fileModeRW)
-				if err != nil {
-					errs[i] = fmt.Errorf(
-						"failed to open output file [%s]: %w",
-						output, err,
-					)
-					return
-				}
-				defer dst.Close()
+	return collectResults(results, errs), errors.Join(errs...)
+}
 
-				// Write the file to disk
-				_, err = io.Copy(dst, src)
-				if err != nil {
-					errs[i] = fmt.Errorf(
-						"failed to write zipped file[%s] to disk: %w",
-						output, err,
-					)
-					return
-				}
-			}
+// installExtension installs VSIX package `zr` to `extDir`, replacing any
+// existing install
+//
+// The package is extracted to a staging directory alongside `extDir` and only
+// moved into place once complete, so a failed or canceled install never leaves
+// a partial extension behind.
+func installExtension(ctx context.Context, zr *zip.Reader, extDir string) error {
+	// Create the staging directory
+	parent := filepath.Dir(extDir)
+	if err := os.MkdirAll(parent, fileModeRWX); err != nil {
+		return fmt.Errorf("failed to create extension directory [%s]: %w", parent, err)
+	}
+	staging, err := os.MkdirTemp(parent, ".vsx-staging-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := os.Chmod(staging, fileModeRWX); err != nil {
+		return fmt.Errorf("failed to set staging directory permissions: %w", err)
+	}
 
-			echo.Infof(
-				"[%s-%s] @ [%s] install complete to [%s].",
-				pub, id, ver, extDir,
-			)
-		})
+	if err := extractExtension(ctx, zr, staging); err != nil {
+		return err
 	}
 
-	// Wait for all workers to complete
-	wait()
+	// Swap the staged extension into place
+	if err := os.RemoveAll(extDir); err != nil {
+		return fmt.Errorf("failed to remove existing install [%s]: %w", extDir, err)
+	}
+	if err := os.Rename(staging, extDir); err != nil {
+		return fmt.Errorf("failed to move staged install to [%s]: %w", extDir, err)
+	}
 
 	return nil
 }
 
-// TODO: Download progress?
-func DownloadExtensions(g gallery.Gallery, outDir string, cmd argv.Command) error {
-	spawn, wait := goLimit(5)
+// extractExtension unzips the `extension` directory of VSIX package `zr` into
+// `extDir`, stopping early if `ctx` is canceled
+func extractExtension(ctx context.Context, zr *zip.Reader, extDir string) error {
+	for _, zipFile := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Ignore non-`extension`-directory files
+		if !strings.HasPrefix(zipFile.Name, "extension") {
+			echo.Debugf("Skipping file [%s].", zipFile.Name)
+			continue
+		}
+
+		// Slice off the `extension` prefix
+		slash := strings.IndexByte(zipFile.Name, '/')
+		if slash == -1 || slash == len(zipFile.Name)-1 {
+			echo.Debugf("Skipping zipped file [%s](no path suffix).", zipFile.Name)
+			continue
+		}
+		name := zipFile.Name[slash+1:]
+
+		// Refuse paths escaping the extension directory (ex: `../../.bashrc`)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("zipped file [%s] escapes the extension directory", zipFile.Name)
+		}
+
+		// Define the output path
+		output := filepath.Join(extDir, name)
+		echo.Debugf("Outputting file [%s] to [%s].", name, output)
 
+		// Create any requisite directories
+		err := os.MkdirAll(filepath.Dir(output), fileModeRWX)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to create output directory structure: %w",
+				err,
+			)
+		}
+
+		// Get a readable stream to the zipped file
+		src, err := zr.Open(zipFile.Name)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to read zipped file[%s]: %w",
+				zipFile.Name, err,
+			)
+		}
+
+		// Get a writable stream to the on-disk file
+		dst, err := os.OpenFile(output, fileFlagsOverwrite, fileModeRW)
+		if err != nil {
+			src.Close()
+			return fmt.Errorf(
+				"failed to open output file [%s]: %w",
+				output, err,
+			)
+		}
+
+		// Write the file to disk
+		_, err = io.Copy(dst, src)
+		src.Close()
+		dst.Close()
+		if err != nil {
+			return fmt.Errorf(
+				"failed to write zipped file[%s] to disk: %w",
+				output, err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// DownloadOptions configures DownloadExtensions
+type DownloadOptions struct {
+	// OutDir is the directory downloads are written to
+	OutDir string
+
+	// NameTemplate names each downloaded file (see renderName)
+	//
+	// Default: defaultNameTemplate
+	NameTemplate string
+
+	// Name is the file name of a single downloaded extension, overriding
+	// NameTemplate
+	Name string
+
+	// Jobs is the number of downloads run at a time
+	Jobs int
+
+	// Progress receives the progress of each download, if not nil
+	Progress ProgressReporter
+
+	// SHA256 holds the expected hex-encoded SHA-256 digest of each extension,
+	// in order, if not nil
+	SHA256 []string
+
+	// Checksums writes (or updates) a SHA256SUMS file in OutDir
+	Checksums bool
+
+	// Resolve constrains the versions downloaded
+	Resolve ResolveOptions
+
+	// Policy decides which extensions may be downloaded, if set
+	Policy *Policy
+}
+
+// DownloadExtensions downloads each of `inputs` as configured by `opts`
+//
+// Each package's digests are computed before it's written, any package failing
+// to match its expected (`opts.SHA256`) or gallery-reported SHA-256 digest is
+// never written.
+func DownloadExtensions(
+	ctx context.Context,
+	g *Galleries,
+	inputs []string,
+	opts DownloadOptions,
+) ([]Result, error) {
 	// Create the output directory if necessary
-	if err := os.MkdirAll(outDir, fileModeRWX); err != nil {
-		return fmt.Errorf("failed to create output directory[%s]: %w", outDir, err)
+	if err := os.MkdirAll(opts.OutDir, fileModeRWX); err != nil {
+		return nil, fmt.Errorf("failed to create output directory[%s]: %w", opts.OutDir, err)
 	}
 
-	errs := make([]error, len(cmd.Args))
-	for i, input := range cmd.Args {
-		echo.Infof("Processing [%s].", input)
-		spawn(func() {
+	pool := NewPool(ctx, opts.Jobs)
+
+	results := make([]Result, len(inputs))
+	for i, input := range inputs {
+		results[i].Extension = input
+		echo.Debugf("Processing [%s].", input)
+		pool.Go(func(ctx context.Context) error {
 			// Parse the extension input
 			pub, id, ver, err := ParseExtension(input)
 			if err != nil {
-				errs[i] = fmt.Errorf(
+				return fmt.Errorf(
 					"failed to parse extension input[%s]: %s",
 					input, err,
 				)
-				return
 			}
-			// If we got no `ver` value, use the default ('latest')
-			if ver == "" {
-				ver = "latest"
+
+			// Resolve the version to download
+			ext, err := ResolveExtension(ctx, g, pub, id, ver, opts.Resolve)
+			if err != nil {
+				return err
 			}
+			results[i].Version = ext.Version
 
 			// Fetch the extension
-			echo.Infof("Fetching extension [%s] by [%s] @ [%s].", id, pub, ver)
-			stream, err := g.GetExtension(context.Background(), pub, id, ver)
+			echo.Debugf("Fetching extension [%s].", ext)
+			if opts.Progress != nil {
+				ctx = gallery.WithProgress(ctx, opts.Progress.Track(input))
+			}
+			pkg, err := g.GetResolvedExtension(ctx, ext)
 			if err != nil {
-				errs[i] = fmt.Errorf(
+				return fmt.Errorf(
 					"failed to fetch extension: %w",
 					err,
 				)
-				return
 			}
 
-			// Construct the output file path
-			outFilePath := filepath.Join(outDir, fmt.Sprintf("%s.%s-%s.vsix", pub, id, ver))
+			// Check the package's integrity
+			digests, err := verifyPackage(pkg)
+			if err != nil {
+				return err
+			}
+			results[i].SHA256, results[i].SHA512 = digests.SHA256, digests.SHA512
+			if opts.SHA256 != nil {
+				if err := digests.Verify(opts.SHA256[i]); err != nil {
+					return fmt.Errorf("--%s: %w", flagSHA256, err)
+				}
+			}
 
-			// Get a writable file stream to output the extension
-			file, err := os.OpenFile(outFilePath, fileFlagsOverwrite, fileModeRW)
+			// Nothing is downloaded against policy
+			zr, err := zip.NewReader(pkg, pkg.Size())
 			if err != nil {
-				errs[i] = fmt.Errorf(
-					"failed to get writable stream to output file: %w",
-					err,
-				)
-				return
+				return fmt.Errorf("failed to init zip reader: %w", err)
+			}
+			if err := opts.Policy.CheckPackage(ext, zr); err != nil {
+				return err
 			}
-			defer file.Close()
 
-			// Write the vsix package to file
-			n, err := io.Copy(file, stream)
+			// Name the output file after the extension actually received, rather
+			// than the one requested (ex: `latest`)
+			name := opts.Name
+			if name == "" {
+				identity, err := readVSIXIdentity(zr)
+				if err != nil {
+					return err
+				}
+
+				tmpl := cmp.Or(opts.NameTemplate, defaultNameTemplate)
+				if name, err = renderName(tmpl, identity); err != nil {
+					return err
+				}
+			}
+
+			// Construct the output file path
+			outFilePath := filepath.Join(opts.OutDir, name)
+			results[i].Path = outFilePath
+
+			n, err := writeFile(ctx, outFilePath, io.NewSectionReader(pkg, 0, pkg.Size()))
 			if err != nil {
-				errs[i] = fmt.Errorf(
-					"failed to write extension content to disk: %w",
-					err,
-				)
-				return
+				return err
 			}
 
 			echo.Debugf("Wrote [%d] bytes to file.", n)
+			return nil
 		})
 	}
 
 	// Wait for all jobs to complete
-	wait()
+	errs := pool.Wait()
+	if opts.Progress != nil {
+		opts.Progress.Close()
+	}
+	results = collectResults(results, errs)
+
+	if opts.Checksums {
+		errs = append(errs, WriteChecksums(opts.OutDir, results))
+	}
 
-	return errors.Join(errs...)
+	return results, errors.Join(errs...)
+}
+
+// writeFile writes `r` to `path` by way of a `.partial` file, which is removed
+// should the write fail or `ctx` be canceled
+func writeFile(ctx context.Context, path string, r io.Reader) (int64, error) {
+	partial := path + ".partial"
+
+	// Get a writable file stream to output the extension
+	file, err := os.OpenFile(partial, fileFlagsOverwrite, fileModeRW)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"failed to get writable stream to output file: %w",
+			err,
+		)
+	}
+
+	// Write the vsix package to file
+	n, err := io.Copy(file, contextReader{ctx, r})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(partial, path)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return n, fmt.Errorf(
+			"failed to write extension content to disk: %w",
+			err,
+		)
+	}
+
+	return n, nil
+}
+
+// contextReader is an io.Reader which fails once its context is canceled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (self contextReader) Read(p []byte) (int, error) {
+	if err := self.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return self.r.Read(p)
 }
 
 var (
 	ErrQueryFailed = fmt.Errorf("failed extension query")
-	colHeaders     = [...]string{
-		"Name",
-		"Publisher",
-		"Install With",
-		"Installs",
-	}
-	colSizes = [...]int{
-		25,
-		20,
-		50,
-		8,
-	}
-	// Ensure colHeaders and colSizes remain reasonably in sync
-	_ = colSizes[len(colHeaders)-1]
 )
 
-func QueryExtensions(g gallery.Gallery, cmd argv.Command) error {
-	if len(cmd.Args) == 0 {
-		return UsageError("No query terms received.")
+// QueryResult is a single extension matched by a query
+type QueryResult struct {
+	Extension            string  `json:"extension"`
+	DisplayName          string  `json:"display_name"`
+	Publisher            string  `json:"publisher"`
+	PublisherDisplayName string  `json:"publisher_display_name"`
+	Version              string  `json:"version"`
+	Installs             float64 `json:"installs"`
+	Rating               float64 `json:"rating"`
+}
+
+func (QueryResult) Columns() []string {
+	return []string{"Name", "Publisher", "Install With", "Installs"}
+}
+
+func (self QueryResult) Row() []string {
+	installWith := self.Extension
+	if self.Version != "" {
+		installWith += "@" + self.Version
 	}
+	return []string{
+		self.DisplayName,
+		self.PublisherDisplayName,
+		installWith,
+		strconv.FormatFloat(self.Installs, 'f', 0, 64),
+	}
+}
 
-	query := strings.Join(cmd.Args, " ")
+func QueryExtensions(ctx context.Context, g *Galleries, format Format, cmd argv.Command) error {
+	opts, err := ParseQueryOptions(cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
 
-	printRow(colHeaders[:]...)
+	// We need at least one of a search term or a filter to run a query
+	if opts.Empty() {
+		return UsageError("No query terms or filters received.")
+	}
 
-	for meta, err := range g.Query(context.Background(), query) {
+	var results []QueryResult
+	for meta, err := range g.Query(ctx, opts) {
 		if err != nil {
 			return fmt.Errorf("%w: %w", ErrQueryFailed, err)
 		}
 
-		printRow(
-			meta.DisplayName,
-			meta.Publisher.DisplayName,
-			fmt.Sprintf("%s.%s@%s", meta.Publisher.Name, meta.Name, meta.Versions[0].Version),
-			strconv.FormatFloat(meta.Statistics[0].Value, 'f', 0, 64), // Installs
-		)
+		// Galleries may omit versions entirely
+		var version string
+		if len(meta.Versions) > 0 {
+			version = meta.Versions[0].Version
+		}
+
+		results = append(results, QueryResult{
+			Extension:            meta.Publisher.Name + "." + meta.Name,
+			DisplayName:          meta.DisplayName,
+			Publisher:            meta.Publisher.Name,
+			PublisherDisplayName: meta.Publisher.DisplayName,
+			Version:              version,
+			Installs:             meta.Statistics.Installs(),
+			Rating:               meta.Statistics.AverageRating(),
+		})
 	}
 
-	return nil
+	// Remember the identifiers for REPL completion
+	for _, result := range results {
+		recentExtensions.Add(result.Extension)
+	}
+
+	return Render(os.Stdout, format, results)
 }
 
-const (
-	colPadding = "  "
+var (
+	ErrSortBy    = fmt.Errorf("unknown sort field, expected one of: installs, rating, updated, published, name, relevance")
+	ErrSortOrder = fmt.Errorf("unknown sort order, expected one of: asc, desc")
+	ErrLimit     = fmt.Errorf("expected a positive integer")
+
+	sortByNames = map[string]gallery.SortBy{
+		"relevance": gallery.SortByRelevance,
+		"installs":  gallery.SortByInstalls,
+		"rating":    gallery.SortByRating,
+		"updated":   gallery.SortByUpdated,
+		"published": gallery.SortByPublished,
+		"name":      gallery.SortByName,
+	}
+	sortOrderNames = map[string]gallery.SortOrder{
+		"asc":  gallery.SortOrderAscending,
+		"desc": gallery.SortOrderDescending,
+	}
 )
 
-func printRow(values ...string) {
-	for i := range len(colSizes) {
-		value := values[i]
-		valueLen := len(value)
-		colSize := colSizes[i]
-		// TODO: Actually fix the issue with spacing around multi-byte characters
-		// and remove this hack
-		if len(value) != len([]rune(value)) {
-			colSize -= 1
+// ParseQueryOptions assembles the gallery query described by the positional
+// args (search terms) and query flags of `cmd`
+func ParseQueryOptions(cmd argv.Command) (opts gallery.QueryOptions, err error) {
+	opts.Term = strings.Join(cmd.Args, " ")
+
+	if v, ok := cmd.Flag(flagCategory); ok {
+		opts.Category = v[0]
+	}
+
+	// Tags may be provided as repeated flags, comma-separated or both
+	if v, ok := cmd.Flag(flagTag); ok {
+		for _, tags := range v {
+			for tag := range strings.SplitSeq(tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					opts.Tags = append(opts.Tags, tag)
+				}
+			}
 		}
+	}
+
+	if v, ok := cmd.Flag(flagPublisher); ok {
+		opts.Publisher = v[0]
+	}
+
+	if v, ok := cmd.Flag(flagName); ok {
+		opts.ExtensionName = v[0]
+	}
+
+	if v, ok := cmd.Flag(flagPlatform); ok {
+		opts.TargetPlatform = v[0]
+	}
 
-		if valueLen > colSize {
-			// If the value is oversized, truncate and write ellipses to indicate
-			// truncation
-			fmt.Printf("%-*s", colSize, value[:colSize-3]+"...")
+	if v, ok := cmd.Flag(flagSort); ok {
+		if opts.SortBy, ok = sortByNames[strings.ToLower(v[0])]; !ok {
+			return opts, fmt.Errorf("--%s [%s]: %w", flagSort, v[0], ErrSortBy)
+		}
+	}
 
-		} else if valueLen == colSize {
-			// If the value matches exactly, just write it out normally
-			fmt.Printf("%-*s", colSize, value)
+	if v, ok := cmd.Flag(flagSortOrder); ok {
+		if opts.SortOrder, ok = sortOrderNames[strings.ToLower(v[0])]; !ok {
+			return opts, fmt.Errorf("--%s [%s]: %w", flagSortOrder, v[0], ErrSortOrder)
+		}
+	}
 
-		} else if valueLen < colSize {
-			// If the value is undersized, pad to the right with spaces
-			fmt.Printf("%-*s", colSize, value)
+	if v, ok := cmd.Flag(flagLimit); ok {
+		limit, err := strconv.Atoi(v[0])
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("--%s [%s]: %w", flagLimit, v[0], ErrLimit)
 		}
+		opts.Limit = limit
+	}
 
-		// For all but the final column, pad between columns
-		if i < len(colSizes)-1 {
-			fmt.Print(colPadding)
+	if v, ok := cmd.Flag(flagPageSize); ok {
+		size, err := strconv.ParseUint(v[0], 10, 16)
+		if err != nil || size < 1 {
+			return opts, fmt.Errorf("--%s [%s]: %w", flagPageSize, v[0], ErrLimit)
 		}
+		opts.PageSize = uint16(size)
 	}
 
-	// Finish the row with a newline
-	fmt.Println()
+	return opts, nil
 }
 
 func UsageError(msg string, values ...any) error {
@@ -373,32 +902,206 @@ func Usage() string {
   ┃ usernamehw -> Extension Publisher
   ┃  errorlens -> Extension ID
   ┃    @3.26.0 -> Optional, allows specific version extension installation
-  ┃               If not provided, a default of 'latest' will be used
+  ┃               If not provided, a default of 'latest' will be used,
+  ┃               resolved to the newest stable version published for
+  ┃               the target platform and supported by '--engine'
+  ┃               Also accepts ranges ('@^1.4', '@~2.3.1', '@>=1.0 <2.0'),
+  ┃               '@latest-stable' and '@prerelease' (newest, pre-releases
+  ┃               included)
   ┗━
 
 >> Commands
 
-   install   Download an extension and install it.
-   download  Download the extension and output the .vsix file to disk.
+   install   Download an extension and install it. With no extensions, the
+             project's declared extensions are installed.
+   download  Download the extension and output the .vsix file to disk. With
+             no extensions, the project's declared extensions are downloaded.
+             Progress is written to stderr: as progress bars on a terminal,
+             otherwise as periodic text (or JSON with '--output-format json').
    query     Query the extension catalog.
+   info      Display gallery details of an extension.
+   list      List installed extensions.
+   outdated  List installed extensions with a newer version available
+             ('--changelog' adds the changes each update brings).
+   editors   List known editors and whether each was detected.
+   asset     Print an asset of a single extension (see '--type'), for
+             example: 'vsx asset usernamehw.errorlens --type changelog'.
+   changelog Print the changelog sections of extensions between the
+             installed version (or '--from') and the latest (or '--to').
+   inspect   Analyze local .vsix files without installing them: identity,
+             engines, activation events, contributions, dependencies,
+             native binaries (ELF, Mach-O, PE) and a size breakdown.
+   diff      Compare two extension versions (or local .vsix files), for
+             example: 'vsx diff usernamehw.errorlens@3.25.0
+             usernamehw.errorlens@3.26.0'. Reports new native binaries,
+             package.json changes (engines, activation events,
+             contributions, API proposals, capabilities, dependencies) and
+             added, removed and modified files. Fetched packages are
+             cached in 'cache_dir'.
+   policy    Enforce the policy file set by 'policy_file':
+               policy check          Evaluate every installed extension,
+                                     failing if any violate the policy.
+             Policy files (JSON, or TOML ending in '.toml') hold a
+             'default' action ('allow' or 'deny') and ordered 'rules',
+             the first matching rule deciding. Each rule has an 'action'
+             and any of: 'publisher' and 'extension' globs, a 'version'
+             range, 'verified', 'license' globs, 'native' and a 'reason'.
+             'install' and 'download' refuse extensions the policy denies.
+             Project config files can't set 'policy_file'.
+   audit     Score the capability risk of extensions, local .vsix files,
+             every installed extension ('--installed') or the project's
+             declared extensions ('--lockfile'): activation on startup
+             ('*', 'onStartupFinished'), bundled executables, lifecycle
+             scripts, terminal profiles, task providers, debuggers,
+             'extensionKind' and network dependencies.
+   licenses  Report the licenses of extensions, local .vsix files, every
+             installed extension ('--installed') or the project's declared
+             extensions ('--lockfile'), identified by SPDX expression from
+             package.json or the package's license file. Proprietary and
+             unidentified licenses are flagged.
+   sbom      Write a software bill of materials (CycloneDX or SPDX JSON,
+             see '--format') of every installed extension, or of
+             extensions, local .vsix files and the project's declared
+             extensions ('--lockfile'), with package URLs, versions,
+             SHA-256 digests (of installed extensions, when cached in
+             'cache_dir') and licenses. '--npm' adds the npm packages each
+             extension bundles in 'node_modules'.
+   publish   Validate local .vsix files and publish them to the
+             '--gallery' selected, or else the only gallery setting its
+             "publish" key, for example: 'vsx publish tools-1.0.0.vsix
+             --gallery internal --token TOKEN'.
+             Open VSX ('/api/-/publish') and Marketplace-compatible
+             galleries are supported (see 'Galleries' below).
+             '--dry-run' only validates.
+   shell     Start an interactive prompt (also the default with no command).
+   config    Manage persisted configuration:
+               config get KEY        Print the effective value of KEY.
+               config set KEY VALUE  Persist VALUE for KEY.
+               config unset KEY      Remove KEY from the config file.
+               config list           List every key and its effective value
+                                     ('--show-origin' adds where each value
+                                     came from).
+               config path           Print the config file path.
+               config edit           Open the config file in $VISUAL/$EDITOR.
+             Keys: extensions_dir, editor, portable_dir, gallery_scheme,
+             gallery_host, gallery, engine, os, arch, request_timeout,
+             hist_file_path, cache_dir, policy_file
 
 >> Flags
 
   --extension-dir, -xd  The local file path to your
                         '.vscode/extensions' directory.
-                        Default:
+                        Default: the first detected editor (see
+                        'vsx editors'), starting with:
                         1. ~/.vscode-oss/extensions
                         2. ~/.vscode/extensions
+  --editor              The editor(s) to target when no '--extension-dir'
+                        is provided. May be repeated or comma-separated,
+                        'all' selects every detected editor. One of:
+                        'vscodium', 'vscode', 'insiders', 'cursor',
+                        'windsurf', 'vscode-server', 'code-server',
+                        'portable' or 'all'.
+  --portable-dir        The 'data' directory of the portable VS Code install
+                        '--editor portable' targets.
+                        Default: $VSCODE_PORTABLE
   --gallery-scheme      The URI scheme for requests to the Gallery
                         ('HTTP' or 'HTTPS').
                         Default: HTTPS
   --gallery-host        The hostname of the extension Gallery
                         (example: my.gallery.com).
-  --output,        -o   If the command provided is 'download', '--output' is 
-                        where the .vsix package will be saved. 
-                        Default: './[publisherID]-[extensionID].[version].vsix'
+  --gallery             The name of a configured gallery profile to use
+                        exclusively (see 'Galleries' below).
+  --output,        -o   If the command provided is 'download', the directory
+                        .vsix packages are saved to, or the file name when
+                        ending in '.vsix' (one extension only).
+                        Default: the working directory
+                        If the command provided is 'asset' or 'sbom', the
+                        file written.
+                        Default: stdout
+  --from                The version 'changelog' shows changes after.
+                        Default: the installed version
+  --to                  The version 'changelog' shows changes up to (one
+                        extension only), also accepting ranges.
+                        Default: the latest version
+  --changelog           Include the changelog sections between the
+                        installed and latest versions in 'outdated'.
+  --installed           Include every installed extension in 'audit',
+                        'licenses' and 'sbom'.
+  --lockfile            Include each of the project's declared extensions
+                        in 'audit', 'licenses' and 'sbom', every one
+                        pinned to an exact version (ex: 'golang.go@0.45.0').
+  --format              The format of the bill of materials written by
+                        'sbom'. One of: 'cyclonedx' or 'spdx'.
+                        Default: cyclonedx
+  --npm                 Include the npm packages bundled by each extension
+                        in 'sbom'.
+  --token               The access token 'publish' authenticates with.
+                        Default: $VSX_PUBLISH_TOKEN
+  --dry-run             Validate packages with 'publish' without publishing
+                        them.
+  --type                The asset printed by 'asset'. One of: 'manifest',
+                        'readme', 'changelog', 'license', 'icon' or
+                        'icon-small', or a full gallery asset type
+                        (example: 'Microsoft.VisualStudio.Code.Manifest').
+  --name-template       The file name of each package downloaded, with
+                        placeholders '{publisher}', '{name}', '{version}'
+                        and '{platform}' ('universal' if not platform
+                        specific), taken from the package received.
+                        Default: '{publisher}.{name}-{version}.vsix'
   --debug,         -d   Enables additional logging for troubleshooting
                         purposes.
+  --output-format       The format of command output. One of: 'table',
+                        'json', 'jsonl', 'csv' or 'yaml'.
+                        Default: table
+  --json                Shorthand for '--output-format json'.
+  --save                Persist the configuration values provided as flags
+                        (ex: '--gallery-host') to the config file.
+  --sha256              The expected SHA-256 digest of each extension
+                        downloaded, in order. May be repeated. Packages
+                        not matching (or not matching the digest reported
+                        by the gallery, if any) are not written.
+  --checksums           Write (or update) a 'SHA256SUMS' file alongside
+                        downloads.
+  --verify              Recheck the files listed in the provided
+                        'SHA256SUMS' file instead of downloading
+                        (example: 'vsx download --verify SHA256SUMS').
+  --engine              The editor version (example: '1.95.0') installed
+                        and downloaded versions must support. Newer
+                        versions requiring a later editor are skipped,
+                        including by 'outdated' and 'info'.
+                        Default: unchecked
+  --pre-release         Allow 'latest' and version ranges to resolve to a
+                        pre-release version, including the latest version
+                        'outdated' and 'info' report.
+  --jobs,          -j   The number of extensions processed concurrently
+                        by 'install', 'download' and 'outdated'.
+                        Default: 5
+  --timeout             Abandon the command once the provided duration
+                        has elapsed (example: '2m'). Ctrl-C also abandons
+                        the running command, removing any partial
+                        downloads or installs. Individual gallery requests
+                        are bounded by the 'request_timeout' config key.
+
+>> Query Flags
+
+  --category            Only return extensions in the provided category
+                        (example: 'Programming Languages').
+  --tag                 Only return extensions carrying the provided tag.
+                        May be repeated or comma-separated.
+  --publisher           Only return extensions by the provided publisher.
+  --name                Only return the extension with the exact provided
+                        identifier (example: 'usernamehw.errorlens').
+  --platform            Only return extensions published for the provided
+                        target platform (example: 'linux-x64').
+  --sort                Sort results by one of: 'installs', 'rating',
+                        'updated', 'published', 'name' or 'relevance'.
+                        Default: relevance
+  --sort-order          Sort results in 'asc' or 'desc' order.
+  --limit               The maximum number of results to return. Results
+                        are paged until the limit is reached.
+                        Default: a single page
+  --page-size           The number of results requested per page.
+                        Default: 20
 
 >> Environment Variables
 
@@ -421,5 +1124,64 @@ func Usage() string {
   VSX_EXTENSION_DIR   The local file path to your '.vscode/extensions'
                       directory.
                       Flag: --extension-dir, -xd
+
+  VSX_GALLERY         The name of a configured gallery profile to use
+                      exclusively.
+                      Flag: --gallery
+
+  VSX_EDITOR          The editor(s) to target when no extension directory is
+                      provided.
+                      Flag: --editor
+
+  VSX_PUBLISH_TOKEN   The access token 'publish' authenticates with.
+                      Flag: --token
+
+>> Galleries
+
+  Additional named galleries and routing rules may be added to the config file
+  ('vsx config edit'):
+
+  ┏━
+  ┃ "galleries": [
+  ┃   { "name": "internal", "host": "gallery.ourcorp.com", "priority": -1 }
+  ┃ ],
+  ┃ "routes": [
+  ┃   { "pattern": "ourcorp.*", "gallery": "internal" }
+  ┃ ]
+  ┗━
+
+  Extensions are looked up in each gallery by priority (lowest first, the
+  'gallery_host' gallery is 0), falling back to the next gallery when not
+  found. Extensions matching a route resolve from the routed gallery only.
+  Queries use the highest priority gallery.
+
+  A gallery's "publish" key ('openvsx' or 'marketplace') sets the API 'publish'
+  uploads with, by default 'openvsx' for open-vsx.org and 'marketplace'
+  otherwise.
+
+>> Project Configuration
+
+  A '.vsx.json' or 'vsx.toml' file in the working directory (or the nearest
+  parent directory holding one) configures vsx for a project. It accepts the
+  same keys as the config file, along with the project's extensions:
+
+  ┏━
+  ┃ # vsx.toml
+  ┃ editor = "vscodium"
+  ┃ os = "linux"
+  ┃ arch = "x64"
+  ┃ extensions = [
+  ┃   "golang.go",
+  ┃   "usernamehw.errorlens@3.26.0",
+  ┃ ]
+  ┃
+  ┃ [[galleries]]
+  ┃ name = "internal"
+  ┃ host = "gallery.ourcorp.com"
+  ┃ priority = -1
+  ┗━
+
+  Values are layered, each superseding the last: defaults, the config file,
+  the project config file, environment variables and finally flags.
 `
 }
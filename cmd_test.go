@@ -0,0 +1,302 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/vsx/gallery"
+	"github.com/illbjorn/zest"
+)
+
+const testVSIXManifest = `<?xml version="1.0" encoding="utf-8"?>
+<PackageManifest Version="2.0.0" xmlns="http://schemas.microsoft.com/developer/vsx-schema/2011">
+  <Metadata>
+    <Identity Language="en-US" Id="tools" Version="1.0.0" Publisher="ourcorp" TargetPlatform="linux-x64"/>
+  </Metadata>
+</PackageManifest>`
+
+// testVSIX produces a VSIX package of extension `ourcorp.tools@1.0.0`
+func testVSIX(t *testing.T) []byte {
+	return testZip(t, map[string]string{
+		vsixManifestName:         testVSIXManifest,
+		"extension/package.json": `{"name":"tools","publisher":"ourcorp","version":"1.0.0"}`,
+	})
+}
+
+// testZip produces a zip archive of `files`, mapping names to content
+func testZip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testVersions are the versions the test gallery reports for every extension
+const testVersions = `[
+  {"version": "1.1.0", "properties": [{"key": "Microsoft.VisualStudio.Code.PreRelease", "value": "true"}]},
+  {"version": "1.0.0", "targetPlatform": "linux-x64"},
+  {"version": "1.0.0"},
+  {"version": "0.9.0"}
+]`
+
+// testPackageGallery starts a gallery serving a VSIX package for every
+// extension except those of publisher `missing`, reporting a bad checksum for
+// those of publisher `tampered`
+func testPackageGallery(t *testing.T) *Galleries {
+	vsix := testVSIX(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Version queries
+		if r.Method == http.MethodPost {
+			var query gallery.QueryRequest
+			_ = json.NewDecoder(r.Body).Decode(&query)
+			var name string
+			for _, c := range query.Filters[0].Criteria {
+				if c.FilterType == gallery.QueryFilterTypeExtensionName {
+					name = c.Value
+				}
+			}
+			pub, id, _ := strings.Cut(name, ".")
+			if pub == "missing" {
+				_, _ = io.WriteString(w, `{"results": [{"extensions": []}]}`)
+				return
+			}
+			_, _ = fmt.Fprintf(w,
+				`{"results": [{"extensions": [{"publisher": {"publisherName": %q}, "extensionName": %q, "versions": %s}]}]}`,
+				pub, id, testVersions,
+			)
+			return
+		}
+
+		if strings.Contains(r.URL.Path, "/publisher/missing/") {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/assetbyname/"+gallery.AssetChangelog) {
+			w.Header().Set("Content-Type", "text/markdown")
+			_, _ = io.WriteString(w, "# Changelog\n")
+			return
+		}
+		if strings.Contains(r.URL.Path, "/publisher/tampered/") {
+			w.Header().Set("X-Checksum-Sha256", strings.Repeat("0", 64))
+		}
+		_, _ = w.Write(vsix)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	g, err := NewGalleries(&Config{GalleryScheme: u.Scheme, GalleryHost: u.Host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestInstallExtensions(t *testing.T) {
+	z := zest.New(t)
+	g := testPackageGallery(t)
+
+	extDirs := []string{t.TempDir(), t.TempDir()}
+	inputs := []string{"ourcorp.tools@1.0.0", "ourcorp.lint", "missing.ext", "bad", "tampered.tools"}
+	results, err := InstallExtensions(context.Background(), g, extDirs, inputs, InstallOptions{Jobs: 2})
+
+	z.Assert(errors.Is(err, gallery.ErrNotFound), "expected not found, got [%v]", err)
+	z.Assert(errors.Is(err, ErrChecksum), "expected a checksum mismatch, got [%v]", err)
+	z.Assert(len(results) == len(inputs)*len(extDirs), "expected [%d] results, got [%d]", len(inputs)*len(extDirs), len(results))
+	for i, result := range results {
+		want := statusSuccess
+		if i >= 2*len(extDirs) {
+			want = statusFailed
+		}
+		z.Assert(result.Status == want, "[%s]: expected [%s], got [%s] (%s)", result.Extension, want, result.Status, result.Error)
+	}
+
+	// Each target holds the extracted package and no staging directories
+	for _, extDir := range extDirs {
+		_, err := os.Stat(filepath.Join(extDir, "ourcorp.tools-1.0.0", "package.json"))
+		z.Assert(err == nil, "expected an installed package.json, got [%v]", err)
+		staged, _ := filepath.Glob(filepath.Join(extDir, ".vsx-staging-*"))
+		z.Assert(len(staged) == 0, "expected no staging directories, got %q", staged)
+
+		// Tampered packages are never installed
+		tampered, _ := filepath.Glob(filepath.Join(extDir, "tampered.*"))
+		z.Assert(len(tampered) == 0, "expected no tampered install, got %q", tampered)
+	}
+}
+
+func TestDownloadExtensions(t *testing.T) {
+	z := zest.New(t)
+	g := testPackageGallery(t)
+
+	outDir := t.TempDir()
+	inputs := []string{"ourcorp.tools@1.0.0", "missing.ext"}
+	progress := &testProgress{done: make(map[string]int64)}
+	opts := DownloadOptions{OutDir: outDir, Jobs: 5, Progress: progress, Checksums: true}
+	results, err := DownloadExtensions(context.Background(), g, inputs, opts)
+
+	z.Assert(errors.Is(err, gallery.ErrNotFound), "expected not found, got [%v]", err)
+	z.Assert(results[0].Status == statusSuccess, "expected success, got [%s] (%s)", results[0].Status, results[0].Error)
+	z.Assert(results[1].Status == statusFailed, "expected failure, got [%s]", results[1].Status)
+
+	_, err = zip.OpenReader(filepath.Join(outDir, "ourcorp.tools-1.0.0.vsix"))
+	z.Assert(err == nil, "expected a readable package, got [%v]", err)
+	partial, _ := filepath.Glob(filepath.Join(outDir, "*.partial"))
+	z.Assert(len(partial) == 0, "expected no partial downloads, got %q", partial)
+
+	// Progress is reported through to completion
+	size := int64(len(testVSIX(t)))
+	got := progress.done[inputs[0]]
+	z.Assert(got == size, "expected [%d] bytes reported done, got [%d]", size, got)
+	z.Assert(progress.closed, "expected the progress reporter to be closed")
+
+	// Downloads are checked against the checksum manifest
+	verified, err := VerifyChecksums(context.Background(), filepath.Join(outDir, checksumFileName))
+	z.Assert(err == nil, "expected no error, got [%v]", err)
+	z.Assert(len(verified) == 1 && verified[0].SHA256 == results[0].SHA256, "unexpected verify results %+v", verified)
+
+	// Mismatched checksums fail without writing the package
+	bad := strings.Repeat("f", 64)
+	opts = DownloadOptions{OutDir: outDir, Jobs: 1, SHA256: []string{bad, results[0].SHA256}}
+	results, err = DownloadExtensions(context.Background(), g, []string{"ourcorp.lint", "tampered.ext"}, opts)
+	z.Assert(errors.Is(err, ErrChecksum), "expected a checksum mismatch, got [%v]", err)
+	z.Assert(results[0].Status == statusFailed && results[1].Status == statusFailed, "expected failures, got %+v", results)
+	written, _ := filepath.Glob(filepath.Join(outDir, "*.vsix"))
+	z.Assert(len(written) == 1, "expected only the first package, got %q", written)
+
+	// The received extension's identity names the file, `-o` names it outright
+	opts = DownloadOptions{OutDir: outDir, Jobs: 1, NameTemplate: "{name}@{platform}-{version}.vsix"}
+	results, err = DownloadExtensions(context.Background(), g, []string{"ourcorp.tools"}, opts)
+	z.Assert(err == nil, "expected no error, got [%v]", err)
+	z.Assert(results[0].Version == "1.0.0", "expected version [1.0.0], got [%s]", results[0].Version)
+	want := filepath.Join(outDir, "tools@linux-x64-1.0.0.vsix")
+	z.Assert(results[0].Path == want, "expected [%s], got [%s]", want, results[0].Path)
+
+	opts = DownloadOptions{OutDir: outDir, Jobs: 1, Name: "pinned.vsix"}
+	results, err = DownloadExtensions(context.Background(), g, []string{"ourcorp.tools"}, opts)
+	z.Assert(err == nil, "expected no error, got [%v]", err)
+	want = filepath.Join(outDir, "pinned.vsix")
+	z.Assert(results[0].Path == want, "expected [%s], got [%s]", want, results[0].Path)
+
+	// Canceled downloads never start
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = DownloadExtensions(ctx, g, []string{"ourcorp.lint"}, DownloadOptions{OutDir: outDir, Jobs: 1})
+	z.Assert(errors.Is(err, context.Canceled), "expected canceled, got [%v]", err)
+}
+
+// testProgress records the bytes read by each transfer once done
+type testProgress struct {
+	mu     sync.Mutex
+	done   map[string]int64
+	closed bool
+}
+
+func (self *testProgress) Track(name string) gallery.ProgressFunc {
+	return func(p gallery.Progress) {
+		if p.Done {
+			self.mu.Lock()
+			self.done[name] = p.Read
+			self.mu.Unlock()
+		}
+	}
+}
+
+func (self *testProgress) Close() { self.closed = true }
+
+func TestGetAsset(t *testing.T) {
+	z := zest.New(t)
+	g := testPackageGallery(t)
+
+	asset, err := g.GetAsset(t.Context(), "ourcorp", "tools", "1.0.0", "", gallery.AssetChangelog)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(string(asset.Data) == "# Changelog\n", "unexpected changelog [%s]", asset.Data)
+	z.Assert(asset.ContentType == "text/markdown", "unexpected content type [%s]", asset.ContentType)
+
+	_, err = g.GetAsset(t.Context(), "missing", "tools", "1.0.0", "", gallery.AssetChangelog)
+	z.Assert(errors.Is(err, gallery.ErrNotFound), "expected [%v], got [%v]", gallery.ErrNotFound, err)
+}
+
+func TestPool(t *testing.T) {
+	z := zest.New(t)
+
+	// No more than `size` jobs run at once
+	var running, peak atomic.Int32
+	pool := NewPool(context.Background(), 3)
+	for range 20 {
+		pool.Go(func(context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			defer running.Add(-1)
+			return nil
+		})
+	}
+	errs := pool.Wait()
+	z.Assert(len(errs) == 20, "expected [20] errors, got [%d]", len(errs))
+	z.Assert(peak.Load() <= 3, "expected at most [3] concurrent jobs, got [%d]", peak.Load())
+
+	// Errors and panics are reported in submission order
+	pool = NewPool(context.Background(), 2)
+	pool.Go(func(context.Context) error { return nil })
+	pool.Go(func(context.Context) error { panic("boom") })
+	pool.Go(func(context.Context) error { return ErrJobs })
+	errs = pool.Wait()
+	z.Assert(errs[0] == nil, "expected no error, got [%v]", errs[0])
+	z.Assert(errors.Is(errs[1], ErrPanic), "expected a panic, got [%v]", errs[1])
+	z.Assert(errors.Is(errs[2], ErrJobs), "expected [%v], got [%v]", ErrJobs, errs[2])
+}
+
+func TestParseQueryOptions(t *testing.T) {
+	z := zest.New(t)
+
+	cmd, err := argv.Parse([]string{
+		"query", "python", "lint",
+		"--tag", "linters,python", "--tag", "formatters",
+		"--sort", "installs", "--limit", "30", "--page-size", "10",
+	})
+	z.Assert(err == nil, "unexpected error: %s", err)
+	opts, err := ParseQueryOptions(cmd)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(opts.Term == "python lint", "unexpected term [%s]", opts.Term)
+	z.Assert(slices.Equal(opts.Tags, []string{"linters", "python", "formatters"}), "unexpected tags %q", opts.Tags)
+	z.Assert(opts.SortBy == gallery.SortByInstalls, "unexpected sort [%v]", opts.SortBy)
+	z.Assert(opts.Limit == 30 && opts.PageSize == 10, "unexpected limit [%d] and page size [%d]", opts.Limit, opts.PageSize)
+
+	for _, args := range [][]string{
+		{"query", "--limit", "0"},
+		{"query", "--page-size", "70000"},
+		{"query", "--sort", "stars"},
+	} {
+		cmd, _ := argv.Parse(args)
+		_, err := ParseQueryOptions(cmd)
+		z.Assert(err != nil, "%q: expected an error", args)
+	}
+}
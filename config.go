@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/illbjorn/argv"
 )
@@ -24,6 +25,20 @@ var (
 )
 
 func LoadConfigFile() (*Config, error) {
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// For any values left blank, apply reasonable defaults
+	cfg = applyConfigDefaults(cfg)
+
+	return cfg, nil
+}
+
+// ReadConfigFile loads the config file exactly as persisted, without applying
+// defaults
+func ReadConfigFile() (*Config, error) {
 	cfg := new(Config)
 
 	// Attempt to load the config from file
@@ -47,9 +62,6 @@ func LoadConfigFile() (*Config, error) {
 		return nil, fmt.Errorf("%w: %w", ErrDecodeConfig, err)
 	}
 
-	// For any values left blank, apply reasonable defaults
-	cfg = applyConfigDefaults(cfg)
-
 	return cfg, nil
 }
 
@@ -80,7 +92,9 @@ func SaveConfigFile(cfg *Config) error {
 	defer f.Close()
 
 	// Attempt the encode
-	err = json.NewEncoder(f).Encode(cfg)
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(cfg)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrEncodeConfig, err)
 	}
@@ -104,6 +118,16 @@ func LoadConfigEnv(cfg *Config) *Config {
 		cfg.ExtensionDir = v
 	}
 
+	const envGallery = "VSX_GALLERY"
+	if v, ok := os.LookupEnv(envGallery); ok {
+		cfg.Gallery = v
+	}
+
+	const envEditor = "VSX_EDITOR"
+	if v, ok := os.LookupEnv(envEditor); ok {
+		cfg.Editor = v
+	}
+
 	const envOS = "VSX_OS"
 	if v, ok := os.LookupEnv(envOS); ok {
 		cfg.OS = v
@@ -130,6 +154,18 @@ type Config struct {
 	// extension gallery
 	GalleryHost string `json:"gallery_host"`
 
+	// Editor selects the editor(s) targeted by the `install`, `list` and
+	// `outdated` subcommands (comma-separated), when ExtensionDir is not set
+	Editor string `json:"editor,omitempty"`
+
+	// PortableDir is the `data` directory of a portable VS Code install,
+	// targeted by the `portable` editor (defaults to `$VSCODE_PORTABLE`)
+	PortableDir string `json:"portable_dir,omitempty"`
+
+	// Engine is the editor version installed and downloaded extensions must
+	// support (ex: `1.95.0`), compatibility is not checked if empty
+	Engine string `json:"engine,omitempty"`
+
 	// OS is the targeted extension operating system
 	OS string `json:"os"`
 
@@ -138,6 +174,38 @@ type Config struct {
 
 	// HistFilePath is the path to the history file for REPL command history
 	HistFilePath string `json:"hist_file_path"`
+
+	// CacheDir is the directory fetched extension packages are cached in
+	CacheDir string `json:"cache_dir,omitempty"`
+
+	// PolicyFile is the path to the policy file deciding which extensions may
+	// be installed and downloaded, nothing is restricted if empty
+	PolicyFile string `json:"policy_file,omitempty"`
+
+	// Galleries are additional named extension galleries, resolved alongside
+	// the default gallery (GalleryScheme/GalleryHost) in priority order
+	Galleries []GalleryProfile `json:"galleries,omitempty"`
+
+	// Routes direct extensions matching a pattern to a single named gallery
+	Routes []GalleryRoute `json:"routes,omitempty"`
+
+	// Gallery is the name of the gallery to use exclusively, bypassing
+	// priority order and routes
+	Gallery string `json:"gallery,omitempty"`
+
+	// RequestTimeout bounds each individual gallery request (ex: `30s`), no
+	// limit is applied if empty
+	RequestTimeout string `json:"request_timeout,omitempty"`
+
+	// Extensions is the set of extensions declared by a project config file,
+	// installed or downloaded when `install` or `download` receive none
+	Extensions []string `json:"extensions,omitempty"`
+
+	// origins maps each configuration key to the source of its value
+	origins map[string]string
+
+	// project is the path to the project config file in effect, if any
+	project string
 }
 
 func applyConfigDefaults(cfg *Config) *Config {
@@ -150,6 +218,12 @@ func applyConfigDefaults(cfg *Config) *Config {
 	if cfg.HistFilePath == "" {
 		cfg.HistFilePath, _ = cfgFile(".history")
 	}
+	// If we don't have a cache directory, use the user cache directory
+	if cfg.CacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			cfg.CacheDir = filepath.Join(dir, app)
+		}
+	}
 
 	return cfg
 }
@@ -188,6 +262,22 @@ func MergeInputs(cfg *Config, cmd argv.Command) *Config {
 		cfg.GalleryHost = v[0]
 	}
 
+	if v, ok := cmd.Flag(flagGallery); ok {
+		cfg.Gallery = v[0]
+	}
+
+	if v, ok := cmd.Flag(flagEditor); ok {
+		cfg.Editor = strings.Join(v, ",")
+	}
+
+	if v, ok := cmd.Flag(flagPortableDir); ok {
+		cfg.PortableDir = v[0]
+	}
+
+	if v, ok := cmd.Flag(flagEngine); ok {
+		cfg.Engine = v[0]
+	}
+
 	if v, ok := cmd.Flag(flagOS); ok {
 		cfg.OS = v[0]
 	}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/illbjorn/argv"
+)
+
+type ConfigCMD = string
+
+const (
+	// `config` subcommands
+	cmdConfigGet   ConfigCMD = "get"
+	cmdConfigSet   ConfigCMD = "set"
+	cmdConfigUnset ConfigCMD = "unset"
+	cmdConfigList  ConfigCMD = "list"
+	cmdConfigPath  ConfigCMD = "path"
+	cmdConfigEdit  ConfigCMD = "edit"
+)
+
+var (
+	ErrConfigKey   = fmt.Errorf("unknown configuration key")
+	ErrConfigValue = fmt.Errorf("invalid configuration value")
+)
+
+// configKey describes a single persisted configuration value
+type configKey struct {
+	// Name is the key's name, matching its `json` tag on Config
+	Name string
+
+	Get func(cfg *Config) string
+
+	// Set validates and applies `value`
+	//
+	// Setting the empty string resets the key to its default.
+	Set func(cfg *Config, value string) error
+}
+
+var (
+	validOS   = []string{"win32", "linux", "alpine", "darwin", "web"}
+	validArch = []string{"x64", "arm64", "armhf", "ia32"}
+
+	configKeys = []configKey{
+		{
+			Name: "extensions_dir",
+			Get:  func(cfg *Config) string { return cfg.ExtensionDir },
+			Set: func(cfg *Config, value string) error {
+				cfg.ExtensionDir = value
+				return nil
+			},
+		},
+		{
+			Name: "editor",
+			Get:  func(cfg *Config) string { return cfg.Editor },
+			Set: func(cfg *Config, value string) error {
+				for name := range strings.SplitSeq(value, ",") {
+					if name = strings.TrimSpace(name); name != "" && name != editorAll {
+						if _, err := lookupEditor(name); err != nil {
+							return err
+						}
+					}
+				}
+				cfg.Editor = value
+				return nil
+			},
+		},
+		{
+			Name: "portable_dir",
+			Get:  func(cfg *Config) string { return cfg.PortableDir },
+			Set: func(cfg *Config, value string) error {
+				cfg.PortableDir = value
+				return nil
+			},
+		},
+		{
+			Name: "gallery_scheme",
+			Get:  func(cfg *Config) string { return cfg.GalleryScheme },
+			Set: func(cfg *Config, value string) error {
+				value = strings.ToLower(value)
+				if value != "" && value != "http" && value != "https" {
+					return fmt.Errorf("expected 'http' or 'https'")
+				}
+				cfg.GalleryScheme = value
+				return nil
+			},
+		},
+		{
+			Name: "gallery_host",
+			Get:  func(cfg *Config) string { return cfg.GalleryHost },
+			Set: func(cfg *Config, value string) error {
+				// Only a host (and optional port) is accepted, a scheme or path is
+				// a common mistake
+				if u, err := url.Parse("//" + value); value != "" && (err != nil || u.Host != value) {
+					return fmt.Errorf("expected a hostname (example: my.gallery.com)")
+				}
+				cfg.GalleryHost = value
+				return nil
+			},
+		},
+		{
+			Name: "gallery",
+			Get:  func(cfg *Config) string { return cfg.Gallery },
+			Set: func(cfg *Config, value string) error {
+				cfg.Gallery = value
+				return nil
+			},
+		},
+		{
+			Name: "engine",
+			Get:  func(cfg *Config) string { return cfg.Engine },
+			Set: func(cfg *Config, value string) error {
+				if _, err := ParseSemver(value); value != "" && err != nil {
+					return fmt.Errorf("expected an editor version (example: 1.95.0)")
+				}
+				cfg.Engine = value
+				return nil
+			},
+		},
+		{
+			Name: "os",
+			Get:  func(cfg *Config) string { return cfg.OS },
+			Set: func(cfg *Config, value string) error {
+				if value != "" && !slices.Contains(validOS, value) {
+					return fmt.Errorf("expected one of: %s", strings.Join(validOS, ", "))
+				}
+				cfg.OS = value
+				return nil
+			},
+		},
+		{
+			Name: "arch",
+			Get:  func(cfg *Config) string { return cfg.Arch },
+			Set: func(cfg *Config, value string) error {
+				if value != "" && !slices.Contains(validArch, value) {
+					return fmt.Errorf("expected one of: %s", strings.Join(validArch, ", "))
+				}
+				cfg.Arch = value
+				return nil
+			},
+		},
+		{
+			Name: "request_timeout",
+			Get:  func(cfg *Config) string { return cfg.RequestTimeout },
+			Set: func(cfg *Config, value string) error {
+				if d, err := time.ParseDuration(value); value != "" && (err != nil || d <= 0) {
+					return fmt.Errorf("expected a positive duration (example: 30s)")
+				}
+				cfg.RequestTimeout = value
+				return nil
+			},
+		},
+		{
+			Name: "hist_file_path",
+			Get:  func(cfg *Config) string { return cfg.HistFilePath },
+			Set: func(cfg *Config, value string) error {
+				cfg.HistFilePath = value
+				return nil
+			},
+		},
+		{
+			Name: "cache_dir",
+			Get:  func(cfg *Config) string { return cfg.CacheDir },
+			Set: func(cfg *Config, value string) error {
+				cfg.CacheDir = value
+				return nil
+			},
+		},
+		{
+			Name: "policy_file",
+			Get:  func(cfg *Config) string { return cfg.PolicyFile },
+			Set: func(cfg *Config, value string) error {
+				cfg.PolicyFile = value
+				return nil
+			},
+		},
+	}
+)
+
+func lookupConfigKey(name string) (configKey, error) {
+	for _, key := range configKeys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+
+	names := make([]string, len(configKeys))
+	for i, key := range configKeys {
+		names[i] = key.Name
+	}
+	return configKey{}, fmt.Errorf(
+		"%w [%s], expected one of: %s",
+		ErrConfigKey, name, strings.Join(names, ", "),
+	)
+}
+
+// ConfigEntry is a single configuration key and its value
+type ConfigEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (ConfigEntry) Columns() []string {
+	return []string{"Key", "Value"}
+}
+
+func (self ConfigEntry) Row() []string {
+	return []string{self.Key, self.Value}
+}
+
+// ConfigOriginEntry is a single configuration key, its value and where the
+// value came from
+type ConfigOriginEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+func (ConfigOriginEntry) Columns() []string {
+	return []string{"Key", "Value", "Origin"}
+}
+
+func (self ConfigOriginEntry) Row() []string {
+	return []string{self.Key, self.Value, self.Origin}
+}
+
+// ConfigCommand handles the `config` subcommands
+//
+// `get` and `list` report the effective configuration (config files,
+// environment and flags combined), while `set`, `unset` and `edit` operate on the global config
+// file alone.
+func ConfigCommand(cfg *Config, format Format, cmd argv.Command) error {
+	if len(cmd.Args) == 0 {
+		return UsageError("No config subcommand received.")
+	}
+
+	sub, args := cmd.Args[0], cmd.Args[1:]
+	switch sub {
+	default:
+		return UsageError("Received unknown config subcommand [%s].", sub)
+
+	case cmdConfigPath:
+		path, err := cfgFile(cfgFileName)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrConfigPath, err)
+		}
+		fmt.Println(path)
+		return nil
+
+	case cmdConfigList:
+		if _, ok := cmd.Flag(flagShowOrigin); ok {
+			entries := make([]ConfigOriginEntry, len(configKeys))
+			for i, key := range configKeys {
+				entries[i] = ConfigOriginEntry{
+					Key:    key.Name,
+					Value:  key.Get(cfg),
+					Origin: cfg.origins[key.Name],
+				}
+			}
+			return Render(os.Stdout, format, entries)
+		}
+
+		entries := make([]ConfigEntry, len(configKeys))
+		for i, key := range configKeys {
+			entries[i] = ConfigEntry{Key: key.Name, Value: key.Get(cfg)}
+		}
+		return Render(os.Stdout, format, entries)
+
+	case cmdConfigGet:
+		if len(args) != 1 {
+			return UsageError("Expected exactly one config key.")
+		}
+		key, err := lookupConfigKey(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(key.Get(cfg))
+		return nil
+
+	case cmdConfigSet:
+		if len(args) != 2 {
+			return UsageError("Expected a config key and value.")
+		}
+		return updateConfigFile(args[0], args[1])
+
+	case cmdConfigUnset:
+		if len(args) != 1 {
+			return UsageError("Expected exactly one config key.")
+		}
+		return updateConfigFile(args[0], "")
+
+	case cmdConfigEdit:
+		return editConfigFile()
+	}
+}
+
+// updateConfigFile sets config key `name` to `value` in the config file
+func updateConfigFile(name, value string) error {
+	key, err := lookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+
+	file, err := ReadConfigFile()
+	if errors.Is(err, os.ErrNotExist) {
+		file = new(Config)
+	} else if err != nil {
+		return err
+	}
+
+	if err := key.Set(file, value); err != nil {
+		return fmt.Errorf("%w for [%s]: %w", ErrConfigValue, name, err)
+	}
+
+	return SaveConfigFile(file)
+}
+
+// editConfigFile opens the config file in the user's editor, validating the
+// result once the editor exits
+func editConfigFile() error {
+	path, err := cfgFile(cfgFileName)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConfigPath, err)
+	}
+
+	// Make sure there's a file to edit
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := SaveConfigFile(new(Config)); err != nil {
+			return err
+		}
+	}
+
+	// Prefer `$VISUAL`, then `$EDITOR`, then the platform default
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may carry its own arguments (ex: `code --wait`)
+	fields := strings.Fields(editor)
+	c := exec.Command(fields[0], append(fields[1:], path)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("failed to run editor [%s]: %w", editor, err)
+	}
+
+	return validateConfigFile()
+}
+
+// validateConfigFile checks every value in the config file against its key's
+// validation, along with any gallery profiles and routes
+func validateConfigFile() error {
+	file, err := ReadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, key := range configKeys {
+		if err := key.Set(new(Config), key.Get(file)); err != nil {
+			errs = append(errs, fmt.Errorf("%w for [%s]: %w", ErrConfigValue, key.Name, err))
+		}
+	}
+
+	// Gallery profiles and routes are validated as a whole
+	if _, err := NewGalleries(file); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrConfigValue, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SaveInputs persists the configuration values provided as command-line flags
+// to the config file, if asked to with `--save`
+//
+// Only the flags themselves are persisted, values from the environment are
+// left out.
+func SaveInputs(cmd argv.Command) error {
+	if _, ok := cmd.Flag(flagSave); !ok {
+		return nil
+	}
+
+	file, err := ReadConfigFile()
+	if errors.Is(err, os.ErrNotExist) {
+		file = new(Config)
+	} else if err != nil {
+		return err
+	}
+
+	return SaveConfigFile(MergeInputs(file, cmd))
+}
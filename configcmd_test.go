@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/zest"
+)
+
+// testConfigDir points the user config dir at a temp dir for the test's
+// duration, producing the path of the config file within it
+func testConfigDir(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	path, err := cfgFile(cfgFileName)
+	if err != nil {
+		t.Fatalf("failed to get config file path: %s", err)
+	}
+	return path
+}
+
+func testConfigCommand(z zest.Zester, args ...string) error {
+	cmd, err := argv.Parse(append([]string{cmdConfig}, args...))
+	z.Assert(err == nil, "%q: failed to parse: %s", args, err)
+	return ConfigCommand(new(Config), FormatTable, cmd)
+}
+
+func TestConfigCommand(t *testing.T) {
+	z := zest.New(t)
+	path := testConfigDir(t)
+
+	// Set and unset round-trip to the file
+	err := testConfigCommand(z, cmdConfigSet, "os", "linux")
+	z.Assert(err == nil, "unexpected error: %s", err)
+	err = testConfigCommand(z, cmdConfigSet, "gallery_host", "gallery.ourcorp.com")
+	z.Assert(err == nil, "unexpected error: %s", err)
+	file, err := ReadConfigFile()
+	z.Assert(err == nil, "failed to read [%s]: %s", path, err)
+	z.Assert(file.OS == "linux", "expected os [linux], got [%s]", file.OS)
+	z.Assert(file.GalleryHost == "gallery.ourcorp.com", "expected gallery_host [gallery.ourcorp.com], got [%s]", file.GalleryHost)
+
+	err = testConfigCommand(z, cmdConfigUnset, "os")
+	z.Assert(err == nil, "unexpected error: %s", err)
+	file, err = ReadConfigFile()
+	z.Assert(err == nil, "failed to read [%s]: %s", path, err)
+	z.Assert(file.OS == "", "expected os unset, got [%s]", file.OS)
+	z.Assert(file.GalleryHost == "gallery.ourcorp.com", "expected gallery_host kept, got [%s]", file.GalleryHost)
+
+	// Invalid keys and values are rejected, leaving the file untouched
+	before, _ := os.ReadFile(path)
+	for _, test := range []struct {
+		args []string
+		want error
+	}{
+		{[]string{cmdConfigSet, "colour", "red"}, ErrConfigKey},
+		{[]string{cmdConfigUnset, "colour"}, ErrConfigKey},
+		{[]string{cmdConfigSet, "os", "plan9"}, ErrConfigValue},
+		{[]string{cmdConfigSet, "gallery_host", "https://gallery.ourcorp.com"}, ErrConfigValue},
+		{[]string{cmdConfigSet, "request_timeout", "0s"}, ErrConfigValue},
+	} {
+		err := testConfigCommand(z, test.args...)
+		z.Assert(errors.Is(err, test.want), "%q: expected [%s], got [%v]", test.args, test.want, err)
+	}
+	after, _ := os.ReadFile(path)
+	z.Assert(string(before) == string(after), "expected [%s] untouched, got:\n%s", path, after)
+}
+
+func TestSaveInputs(t *testing.T) {
+	z := zest.New(t)
+	path := testConfigDir(t)
+
+	err := SaveConfigFile(&Config{OS: "linux"})
+	z.Assert(err == nil, "failed to write [%s]: %s", path, err)
+	before, _ := os.ReadFile(path)
+
+	// Flags alone don't rewrite the config file
+	cmd, err := argv.Parse([]string{cmdQuery, "python", "--" + flagOS, "darwin", "--" + flagArch, "arm64"})
+	z.Assert(err == nil, "failed to parse: %s", err)
+	cfg, err := LoadConfig(cmd)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(cfg.OS == "darwin", "expected flag os [darwin], got [%s]", cfg.OS)
+	z.Assert(SaveInputs(cmd) == nil, "unexpected error saving inputs")
+	after, _ := os.ReadFile(path)
+	z.Assert(string(before) == string(after), "expected [%s] untouched without --%s, got:\n%s", path, flagSave, after)
+
+	// With `--save`, they're persisted
+	cmd, err = argv.Parse([]string{cmdQuery, "python", "--" + flagOS, "darwin", "--" + flagSave})
+	z.Assert(err == nil, "failed to parse: %s", err)
+	z.Assert(SaveInputs(cmd) == nil, "unexpected error saving inputs")
+	file, err := ReadConfigFile()
+	z.Assert(err == nil, "failed to read [%s]: %s", filepath.Base(path), err)
+	z.Assert(file.OS == "darwin", "expected os [darwin] saved, got [%s]", file.OS)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/argv"
+)
+
+// Diff entry kinds, in the order reported
+const (
+	diffKindNative       = "native"
+	diffKindEngine       = "engine"
+	diffKindEntrypoint   = "entrypoint"
+	diffKindActivation   = "activation_event"
+	diffKindContribution = "contribution"
+	diffKindProposal     = "api_proposal"
+	diffKindCapability   = "capability"
+	diffKindExtDep       = "extension_dependency"
+	diffKindExtPack      = "extension_pack"
+	diffKindDependency   = "dependency"
+	diffKindFile         = "file"
+)
+
+// Diff entry changes
+const (
+	diffAdded    = "added"
+	diffRemoved  = "removed"
+	diffModified = "modified"
+)
+
+// DiffEntry is a single difference between two versions of an extension
+type DiffEntry struct {
+	Kind   string `json:"kind"`
+	Change string `json:"change"`
+	Item   string `json:"item"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+
+	// SizeDelta is the change in size of file entries, in bytes
+	SizeDelta int64 `json:"size_delta,omitempty"`
+}
+
+func (DiffEntry) Columns() []string {
+	return []string{"Kind", "Change", "Item", "Old", "New", "Delta"}
+}
+
+func (self DiffEntry) Row() []string {
+	delta := ""
+	if self.Kind == diffKindFile {
+		delta = formatBytes(self.SizeDelta)
+		if self.SizeDelta >= 0 {
+			delta = "+" + delta
+		}
+	}
+	return []string{self.Kind, self.Change, self.Item, self.Old, self.New, delta}
+}
+
+// DiffCommand reports the differences between the two extension versions or
+// local VSIX packages in `cmd`
+func DiffCommand(ctx context.Context, g *Galleries, cfg *Config, format Format, cmd argv.Command) error {
+	if len(cmd.Args) != 2 {
+		return UsageError("Expected exactly two extension versions or .vsix files.")
+	}
+	resolve, err := ParseResolveOptions(cfg, cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+	cache := NewPackageCache(cfg)
+
+	var pkgs [2]*VSIXPackage
+	for i, input := range cmd.Args {
+		if pkgs[i], err = loadPackage(ctx, g, cache, resolve, input); err != nil {
+			return err
+		}
+	}
+
+	return Render(os.Stdout, format, DiffPackages(pkgs[0], pkgs[1]))
+}
+
+// loadPackage reads the VSIX package `input` refers to: either a local `.vsix`
+// file or an extension version, fetched by way of `cache`
+func loadPackage(
+	ctx context.Context,
+	g *Galleries,
+	cache PackageCache,
+	opts ResolveOptions,
+	input string,
+) (*VSIXPackage, error) {
+	if strings.EqualFold(filepath.Ext(input), ".vsix") {
+		if _, err := os.Stat(input); err == nil {
+			data, err := os.ReadFile(input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open [%s]: %w", input, err)
+			}
+			return readPackage(input, data)
+		}
+	}
+
+	pub, id, ver, err := ParseExtension(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse extension input [%s]: %w", input, err)
+	}
+	ext, err := ResolveExtension(ctx, g, pub, id, ver, opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetchPackage(ctx, g, cache, ext)
+	if err != nil {
+		return nil, err
+	}
+	return readPackage(ext.String(), data)
+}
+
+// readPackage reads VSIX package `data`, named `name` in errors
+func readPackage(name string, data []byte) (*VSIXPackage, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read [%s] as a zip archive: %w", name, err)
+	}
+	pkg, err := ReadVSIX(zr)
+	if err != nil {
+		return nil, fmt.Errorf("[%s]: %w", name, err)
+	}
+	digests, err := digest(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	pkg.SHA256 = digests.SHA256
+	return pkg, nil
+}
+
+// DiffPackages produces the differences from package `a` to package `b`
+func DiffPackages(a, b *VSIXPackage) []DiffEntry {
+	var entries []DiffEntry
+	add := func(kind, change, item, old, new string) {
+		entries = append(entries, DiffEntry{Kind: kind, Change: change, Item: item, Old: old, New: new})
+	}
+	am, bm := a.Manifest, b.Manifest
+
+	// Native binaries new to `b`, whether added or replacing something else
+	aFiles := make(map[string]VSIXFile, len(a.Files))
+	for _, f := range a.Files {
+		aFiles[f.Name] = f
+	}
+	for _, f := range b.Files {
+		if f.Native != "" && aFiles[f.Name].Native == "" {
+			add(diffKindNative, diffAdded, f.Name, "", f.Native)
+		}
+	}
+
+	entries = append(entries, diffMaps(diffKindEngine, am.Engines, bm.Engines)...)
+	if am.Main != bm.Main {
+		add(diffKindEntrypoint, diffModified, "main", am.Main, bm.Main)
+	}
+	if am.Browser != bm.Browser {
+		add(diffKindEntrypoint, diffModified, "browser", am.Browser, bm.Browser)
+	}
+	entries = append(entries, diffLists(diffKindActivation, am.ActivationEvents, bm.ActivationEvents)...)
+	entries = append(entries, diffContributions(am, bm)...)
+	entries = append(entries, diffLists(diffKindProposal, am.EnabledAPIProposals, bm.EnabledAPIProposals)...)
+	entries = append(entries, diffMaps(diffKindCapability, rawStrings(am.Capabilities), rawStrings(bm.Capabilities))...)
+	entries = append(entries, diffLists(diffKindExtDep, am.ExtensionDependencies, bm.ExtensionDependencies)...)
+	entries = append(entries, diffLists(diffKindExtPack, am.ExtensionPack, bm.ExtensionPack)...)
+	entries = append(entries, diffMaps(diffKindDependency, am.Dependencies, bm.Dependencies)...)
+
+	// Files, by name
+	bFiles := make(map[string]VSIXFile, len(b.Files))
+	for _, f := range b.Files {
+		bFiles[f.Name] = f
+	}
+	names := slices.Collect(maps.Keys(aFiles))
+	for name := range bFiles {
+		if _, ok := aFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		af, inA := aFiles[name]
+		bf, inB := bFiles[name]
+		entry := DiffEntry{Kind: diffKindFile, Item: name, SizeDelta: bf.Size - af.Size}
+		switch {
+		case !inA:
+			entry.Change, entry.New = diffAdded, formatBytes(bf.Size)
+		case !inB:
+			entry.Change, entry.Old = diffRemoved, formatBytes(af.Size)
+		case af.Size != bf.Size || af.CRC32 != bf.CRC32:
+			entry.Change = diffModified
+			entry.Old, entry.New = formatBytes(af.Size), formatBytes(bf.Size)
+		default:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// diffContributions compares the contributions of `a` and `b`, by identifier
+// for the points identifying their contributions and by point otherwise
+func diffContributions(a, b ExtensionManifest) []DiffEntry {
+	var entries []DiffEntry
+	points := slices.Sorted(maps.Keys(a.Contributes))
+	for point := range b.Contributes {
+		if _, ok := a.Contributes[point]; !ok {
+			points = append(points, point)
+		}
+	}
+	slices.Sort(points)
+
+	rawA, rawB := rawStrings(a.Contributes), rawStrings(b.Contributes)
+	for _, point := range points {
+		if contrib, ok := contributionPoints[point]; ok {
+			for _, entry := range diffLists(diffKindContribution, a.Contributions(contrib), b.Contributions(contrib)) {
+				entry.Item = point + ": " + entry.Item
+				entries = append(entries, entry)
+			}
+			continue
+		}
+
+		// Contribution bodies are too large to report as values
+		av, inA := rawA[point]
+		bv, inB := rawB[point]
+		switch {
+		case !inA:
+			entries = append(entries, DiffEntry{Kind: diffKindContribution, Change: diffAdded, Item: point})
+		case !inB:
+			entries = append(entries, DiffEntry{Kind: diffKindContribution, Change: diffRemoved, Item: point})
+		case av != bv:
+			entries = append(entries, DiffEntry{Kind: diffKindContribution, Change: diffModified, Item: point})
+		}
+	}
+
+	return entries
+}
+
+// diffLists reports the items added to and removed from `a` in `b`
+func diffLists(kind string, a, b []string) []DiffEntry {
+	var entries []DiffEntry
+	for _, item := range b {
+		if !slices.Contains(a, item) {
+			entries = append(entries, DiffEntry{Kind: kind, Change: diffAdded, Item: item})
+		}
+	}
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			entries = append(entries, DiffEntry{Kind: kind, Change: diffRemoved, Item: item})
+		}
+	}
+	return entries
+}
+
+// diffMaps reports the keys added to, removed from and modified from `a` in `b`
+func diffMaps(kind string, a, b map[string]string) []DiffEntry {
+	keys := slices.Collect(maps.Keys(a))
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var entries []DiffEntry
+	for _, key := range keys {
+		av, inA := a[key]
+		bv, inB := b[key]
+		switch {
+		case !inA:
+			entries = append(entries, DiffEntry{Kind: kind, Change: diffAdded, Item: key, New: bv})
+		case !inB:
+			entries = append(entries, DiffEntry{Kind: kind, Change: diffRemoved, Item: key, Old: av})
+		case av != bv:
+			entries = append(entries, DiffEntry{Kind: kind, Change: diffModified, Item: key, Old: av, New: bv})
+		}
+	}
+	return entries
+}
+
+// rawStrings produces the compacted JSON of each of `raw`'s values, for
+// comparison
+func rawStrings(raw map[string]json.RawMessage) map[string]string {
+	strs := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var b bytes.Buffer
+		if err := json.Compact(&b, value); err != nil {
+			b.Reset()
+			b.Write(value)
+		}
+		strs[key] = b.String()
+	}
+	return strs
+}
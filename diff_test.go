@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+// testPackage reads a VSIX package of `ourcorp.tools` with `package.json`
+// `packageJSON` and additional `files`
+func testPackage(t *testing.T, packageJSON string, files map[string]string) *VSIXPackage {
+	files[vsixManifestName] = testVSIXManifest
+	files[vsixPackageJSONName] = packageJSON
+	data := testZip(t, files)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := ReadVSIX(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestDiffPackages(t *testing.T) {
+	z := zest.New(t)
+
+	a := testPackage(t, `{
+	  "activationEvents": ["onLanguage:go"],
+	  "contributes": {"commands": [{"command": "tools.run"}], "menus": {"a": 1}},
+	  "dependencies": {"left-pad": "1.0.0", "lodash": "4.0.0"}
+	}`, map[string]string{
+		"extension/dist/extension.js": "console.log(1)",
+		"extension/README.md":         "# Tools",
+	})
+	b := testPackage(t, `{
+	  "activationEvents": ["onLanguage:go", "*"],
+	  "enabledApiProposals": ["terminalDataWriteEvent"],
+	  "contributes": {"commands": [{"command": "tools.stop"}], "menus": {"a": 2}},
+	  "dependencies": {"lodash": "4.1.0"}
+	}`, map[string]string{
+		"extension/dist/extension.js": "console.log(2)",
+		"extension/bin/tool":          "\x7fELF" + strings.Repeat("\x00", 60),
+	})
+
+	var got []string
+	for _, entry := range DiffPackages(a, b) {
+		got = append(got, entry.Kind+" "+entry.Change+" "+entry.Item)
+	}
+	want := []string{
+		"native added extension/bin/tool",
+		"activation_event added *",
+		"contribution added commands: tools.stop",
+		"contribution removed commands: tools.run",
+		"contribution modified menus",
+		"api_proposal added terminalDataWriteEvent",
+		"dependency removed left-pad",
+		"dependency modified lodash",
+		"file removed extension/README.md",
+		"file added extension/bin/tool",
+		"file modified extension/dist/extension.js",
+		"file modified extension/package.json",
+	}
+	z.Assert(slices.Equal(got, want), "expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+}
+
+func TestPackageCache(t *testing.T) {
+	z := zest.New(t)
+	g := testPackageGallery(t)
+	cache := NewPackageCache(&Config{CacheDir: t.TempDir()})
+	ext, err := ResolveExtension(t.Context(), g, "ourcorp", "tools", "1.0.0", ResolveOptions{})
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	primary, _ := g.Primary()
+	z.Assert(ext.Gallery == primary.BaseURL.Host, "expected gallery [%s], got [%s]", primary.BaseURL.Host, ext.Gallery)
+
+	_, ok := cache.Get(ext)
+	z.Assert(!ok, "expected an empty cache")
+
+	data, err := fetchPackage(t.Context(), g, cache, ext)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+
+	cached, ok := cache.Get(ext)
+	z.Assert(ok && bytes.Equal(cached, data), "expected the fetched package to be cached")
+
+	// Packages are cached per gallery
+	other := ext
+	other.Gallery = "gallery.ourcorp.com:8443"
+	_, ok = cache.Get(other)
+	z.Assert(!ok, "expected no package cached for another gallery")
+	z.Assert(cache.Put(t.Context(), other, []byte("other")) == nil, "failed to cache for another gallery")
+	cached, _ = cache.Get(other)
+	z.Assert(string(cached) == "other", "expected the other gallery's package, got [%s]", cached)
+	cached, _ = cache.Get(ext)
+	z.Assert(bytes.Equal(cached, data), "expected the first gallery's package kept")
+
+	// Nor are packages of no known gallery
+	unknown := ext
+	unknown.Gallery = ""
+	z.Assert(cache.Put(t.Context(), unknown, data) == nil, "unexpected error caching")
+	_, ok = cache.Get(unknown)
+	z.Assert(!ok, "expected no package cached without a gallery")
+
+	// Tampered packages are never cached
+	tampered := ResolvedExtension{Publisher: "tampered", Name: "tools", Version: "1.0.0", Gallery: ext.Gallery}
+	_, err = fetchPackage(t.Context(), g, cache, tampered)
+	z.Assert(err != nil, "expected a checksum error")
+	_, ok = cache.Get(tampered)
+	z.Assert(!ok, "expected the tampered package not to be cached")
+}
+
+func TestDiffEntryRow(t *testing.T) {
+	z := zest.New(t)
+
+	// Shrunk files keep their sign, grown ones gain one
+	for _, tc := range []struct {
+		delta int64
+		want  string
+	}{
+		{-1536, "-1.5 KiB"},
+		{1536, "+1.5 KiB"},
+	} {
+		row := DiffEntry{Kind: diffKindFile, SizeDelta: tc.delta}.Row()
+		got := row[len(row)-1]
+		z.Assert(got == tc.want, "[%d]: expected delta [%s], got [%s]", tc.delta, tc.want, got)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/illbjorn/echo"
+)
+
+const (
+	// editorAll selects every detected editor
+	editorAll = "all"
+
+	// editorPortable is the portable VS Code install, located by `portable_dir`
+	// or `$VSCODE_PORTABLE`
+	editorPortable = "portable"
+)
+
+var (
+	ErrNoEditor      = fmt.Errorf("failed to locate an editor extension directory")
+	ErrUnknownEditor = fmt.Errorf("unknown editor")
+	ErrNoPortable    = fmt.Errorf("no portable install, set 'portable_dir' (--portable-dir) or $VSCODE_PORTABLE")
+)
+
+// Editor is a known VS Code flavor
+type Editor struct {
+	// Name identifies the editor with [`--editor`]
+	Name string
+
+	// DisplayName is the editor's human-friendly name
+	DisplayName string
+
+	// Root produces the editor's per-user data directory (the parent of its
+	// `extensions` directory), or the empty string if it can't be determined
+	Root func(paths editorPaths) string
+}
+
+// editorPaths are the locations editor data directories are found relative to
+type editorPaths struct {
+	// home is the user's home directory
+	home string
+
+	// portable is the `data` directory of a portable install, if any
+	portable string
+}
+
+// newEditorPaths produces the editorPaths of the user, the portable install
+// being that of `cfg` and otherwise of `$VSCODE_PORTABLE`
+func newEditorPaths(cfg *Config) (editorPaths, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return editorPaths{}, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return editorPaths{
+		home:     home,
+		portable: cmp.Or(cfg.PortableDir, os.Getenv("VSCODE_PORTABLE")),
+	}, nil
+}
+
+// ExtensionDir produces the editor's extension directory
+func (self Editor) ExtensionDir(paths editorPaths) string {
+	root := self.Root(paths)
+	if root == "" {
+		return ""
+	}
+	return filepath.Join(root, "extensions")
+}
+
+// Detected reports whether the editor appears to be installed for the user
+func (self Editor) Detected(paths editorPaths) bool {
+	root := self.Root(paths)
+	if root == "" {
+		return false
+	}
+	_, err := os.Lstat(root)
+	return err == nil
+}
+
+// homeDir produces an Editor.Root for editors keeping their data in directory
+// `name` beneath the home directory
+func homeDir(name string) func(paths editorPaths) string {
+	return func(paths editorPaths) string {
+		return filepath.Join(paths.home, name)
+	}
+}
+
+// editors is the registry of known editors, in order of preference when
+// auto-detecting
+var editors = []Editor{
+	{
+		Name:        "vscodium",
+		DisplayName: "VSCodium",
+		Root:        homeDir(".vscode-oss"),
+	},
+	{
+		Name:        "vscode",
+		DisplayName: "VS Code",
+		Root:        homeDir(".vscode"),
+	},
+	{
+		Name:        "insiders",
+		DisplayName: "VS Code Insiders",
+		Root:        homeDir(".vscode-insiders"),
+	},
+	{
+		Name:        "cursor",
+		DisplayName: "Cursor",
+		Root:        homeDir(".cursor"),
+	},
+	{
+		Name:        "windsurf",
+		DisplayName: "Windsurf",
+		Root:        homeDir(".windsurf"),
+	},
+	{
+		Name:        "vscode-server",
+		DisplayName: "VS Code Server (Remote SSH)",
+		Root:        homeDir(".vscode-server"),
+	},
+	{
+		Name:        "code-server",
+		DisplayName: "code-server",
+		Root: func(paths editorPaths) string {
+			dataHome := os.Getenv("XDG_DATA_HOME")
+			if dataHome == "" {
+				dataHome = filepath.Join(paths.home, ".local", "share")
+			}
+			return filepath.Join(dataHome, "code-server")
+		},
+	},
+	{
+		// Portable installs keep their data in a `data` directory alongside the
+		// executable, which VS Code exposes as `$VSCODE_PORTABLE`
+		Name:        editorPortable,
+		DisplayName: "VS Code (Portable)",
+		Root: func(paths editorPaths) string {
+			return paths.portable
+		},
+	},
+}
+
+func lookupEditor(name string) (Editor, error) {
+	for _, editor := range editors {
+		if editor.Name == name {
+			return editor, nil
+		}
+	}
+
+	names := make([]string, len(editors))
+	for i, editor := range editors {
+		names[i] = editor.Name
+	}
+	return Editor{}, fmt.Errorf(
+		"%w [%s], expected one of: %s, %s",
+		ErrUnknownEditor, name, strings.Join(names, ", "), editorAll,
+	)
+}
+
+// ExtensionDir produces the extension directory of the first detected editor
+func ExtensionDir(cfg *Config) (string, error) {
+	paths, err := newEditorPaths(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	for _, editor := range editors {
+		if editor.Detected(paths) {
+			extDir := editor.ExtensionDir(paths)
+			echo.Debugf("Using extension directory [%s].", extDir)
+			return extDir, nil
+		}
+		echo.Debugf("Attempted: %s.", editor.Root(paths))
+	}
+
+	return "", ErrNoEditor
+}
+
+// ExtensionDirs produces the extension directories targeted by `cfg`
+//
+// An explicit extension directory takes precedence, followed by the selected
+// editors (`all` selecting every detected editor). Failing both, the first
+// detected editor is used.
+func ExtensionDirs(cfg *Config) ([]string, error) {
+	if cfg.ExtensionDir != "" {
+		return []string{cfg.ExtensionDir}, nil
+	}
+
+	if cfg.Editor == "" {
+		extDir, err := ExtensionDir(cfg)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"received no VSCode extension directory and failed to locate one: %w",
+				err,
+			)
+		}
+		return []string{extDir}, nil
+	}
+
+	paths, err := newEditorPaths(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for name := range strings.SplitSeq(cfg.Editor, ",") {
+		name = strings.TrimSpace(name)
+		if name == editorAll {
+			for _, editor := range editors {
+				if editor.Detected(paths) {
+					dirs = append(dirs, editor.ExtensionDir(paths))
+				}
+			}
+			continue
+		}
+
+		editor, err := lookupEditor(name)
+		if err != nil {
+			return nil, err
+		}
+		extDir := editor.ExtensionDir(paths)
+		switch {
+		case extDir == "" && editor.Name == editorPortable:
+			return nil, ErrNoPortable
+		case extDir == "":
+			return nil, fmt.Errorf("failed to locate the [%s] extension directory", editor.Name)
+		}
+		dirs = append(dirs, extDir)
+	}
+
+	if len(dirs) == 0 {
+		return nil, ErrNoEditor
+	}
+
+	return dirs, nil
+}
+
+// DetectedEditor describes a known editor and whether it was found
+type DetectedEditor struct {
+	Name         string `json:"name"`
+	DisplayName  string `json:"display_name"`
+	Detected     bool   `json:"detected"`
+	ExtensionDir string `json:"extension_dir"`
+}
+
+func (DetectedEditor) Columns() []string {
+	return []string{"Editor", "Name", "Detected", "Extension Dir"}
+}
+
+func (self DetectedEditor) Row() []string {
+	detected := "no"
+	if self.Detected {
+		detected = "yes"
+	}
+	return []string{self.Name, self.DisplayName, detected, self.ExtensionDir}
+}
+
+func ListEditors(cfg *Config, format Format) error {
+	paths, err := newEditorPaths(cfg)
+	if err != nil {
+		return err
+	}
+
+	detected := make([]DetectedEditor, len(editors))
+	for i, editor := range editors {
+		detected[i] = DetectedEditor{
+			Name:         editor.Name,
+			DisplayName:  editor.DisplayName,
+			Detected:     editor.Detected(paths),
+			ExtensionDir: editor.ExtensionDir(paths),
+		}
+	}
+
+	return Render(os.Stdout, format, detected)
+}
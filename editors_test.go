@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/zest"
+)
+
+func TestLookupEditor(t *testing.T) {
+	z := zest.New(t)
+
+	for _, editor := range editors {
+		got, err := lookupEditor(editor.Name)
+		z.Assert(err == nil, "[%s]: unexpected error: %s", editor.Name, err)
+		z.Assert(got.DisplayName == editor.DisplayName, "[%s]: expected [%s], got [%s]", editor.Name, editor.DisplayName, got.DisplayName)
+	}
+
+	_, err := lookupEditor("notepad")
+	z.Assert(errors.Is(err, ErrUnknownEditor), "expected unknown editor, got [%v]", err)
+	_, err = lookupEditor(editorAll)
+	z.Assert(errors.Is(err, ErrUnknownEditor), "expected [%s] to be no editor, got [%v]", editorAll, err)
+}
+
+func TestExtensionDirs(t *testing.T) {
+	z := zest.New(t)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("VSCODE_PORTABLE", "")
+	portable := t.TempDir()
+
+	// Only VS Code and Cursor are installed
+	for _, dir := range []string{".vscode", ".cursor"} {
+		z.Assert(os.Mkdir(filepath.Join(home, dir), 0o755) == nil, "failed to create [%s]", dir)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "extension dir wins",
+			cfg:  Config{ExtensionDir: "/exts", Editor: "vscode"},
+			want: []string{"/exts"},
+		},
+		{
+			name: "first detected",
+			want: []string{filepath.Join(home, ".vscode", "extensions")},
+		},
+		{
+			name: "multiple editors",
+			cfg:  Config{Editor: "vscodium, cursor"},
+			want: []string{
+				filepath.Join(home, ".vscode-oss", "extensions"),
+				filepath.Join(home, ".cursor", "extensions"),
+			},
+		},
+		{
+			name: "all detected",
+			cfg:  Config{Editor: editorAll},
+			want: []string{
+				filepath.Join(home, ".vscode", "extensions"),
+				filepath.Join(home, ".cursor", "extensions"),
+			},
+		},
+		{
+			name: "portable dir",
+			cfg:  Config{Editor: editorPortable, PortableDir: portable},
+			want: []string{filepath.Join(portable, "extensions")},
+		},
+		{
+			name:    "portable unset",
+			cfg:     Config{Editor: "vscode," + editorPortable},
+			wantErr: ErrNoPortable,
+		},
+		{
+			name:    "unknown editor",
+			cfg:     Config{Editor: "notepad"},
+			wantErr: ErrUnknownEditor,
+		},
+	}
+	for _, test := range tests {
+		got, err := ExtensionDirs(&test.cfg)
+		if test.wantErr != nil {
+			z.Assert(errors.Is(err, test.wantErr), "[%s]: expected [%s], got [%v]", test.name, test.wantErr, err)
+			continue
+		}
+		z.Assert(err == nil, "[%s]: unexpected error: %s", test.name, err)
+		z.Assert(slices.Equal(got, test.want), "[%s]: expected %q, got %q", test.name, test.want, got)
+	}
+
+	// `$VSCODE_PORTABLE` locates the portable install without config
+	t.Setenv("VSCODE_PORTABLE", portable)
+	got, err := ExtensionDirs(&Config{Editor: editorPortable})
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(slices.Equal(got, []string{filepath.Join(portable, "extensions")}), "unexpected dirs %q", got)
+}
+
+func TestEditorFlag(t *testing.T) {
+	z := zest.New(t)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	portable := t.TempDir()
+
+	// Repeated and comma-separated editors combine
+	cmd, err := argv.Parse([]string{
+		cmdInstall, "ourcorp.tools@1.0.0",
+		"--" + flagEditor, "vscode,cursor", "--" + flagEditor, editorPortable,
+		"--" + flagPortableDir, portable,
+	})
+	z.Assert(err == nil, "failed to parse: %s", err)
+	cfg := MergeInputs(new(Config), cmd)
+	z.Assert(cfg.Editor == "vscode,cursor,"+editorPortable, "unexpected editor [%s]", cfg.Editor)
+	z.Assert(cfg.PortableDir == portable, "unexpected portable dir [%s]", cfg.PortableDir)
+
+	// Each selected editor is installed to
+	extDirs, err := ExtensionDirs(cfg)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(len(extDirs) == 3, "expected 3 extension dirs, got %q", extDirs)
+	results, err := InstallExtensions(context.Background(), testPackageGallery(t), extDirs, cmd.Args, InstallOptions{Jobs: 1})
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(len(results) == len(extDirs), "expected [%d] results, got [%d]", len(extDirs), len(results))
+	for _, extDir := range extDirs {
+		_, err := os.Stat(filepath.Join(extDir, "ourcorp.tools-1.0.0", "package.json"))
+		z.Assert(err == nil, "[%s]: expected an installed package.json, got [%v]", extDir, err)
+	}
+}
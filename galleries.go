@@ -0,0 +1,267 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	// defaultGalleryName names the gallery described by the top-level
+	// `gallery_scheme` and `gallery_host` configuration values
+	defaultGalleryName = "default"
+)
+
+var (
+	ErrNoGallery      = fmt.Errorf("no extension gallery configured")
+	ErrUnknownGallery = fmt.Errorf("unknown gallery")
+)
+
+// GalleryProfile is a named extension gallery
+type GalleryProfile struct {
+	Name   string `json:"name"`
+	Scheme string `json:"scheme"`
+	Host   string `json:"host"`
+
+	// Priority orders galleries for resolution, lowest first
+	//
+	// The default gallery (`gallery_host`) has priority 0.
+	Priority int `json:"priority"`
+
+	// Publish is the gallery's publish API (`openvsx` or `marketplace`), if
+	// unset detected from its host
+	Publish string `json:"publish,omitempty"`
+}
+
+// GalleryRoute directs extensions matching Pattern to a single gallery
+type GalleryRoute struct {
+	// Pattern is a glob matched (case-insensitively) against extension
+	// identifiers in `publisher.name` form (ex: `ourcorp.*`)
+	Pattern string `json:"pattern"`
+
+	// Gallery is the name of the gallery matching extensions resolve from
+	Gallery string `json:"gallery"`
+}
+
+// NamedGallery is a gallery client alongside its profile name
+type NamedGallery struct {
+	Name string
+
+	// Publish is the gallery's configured publish API, if any
+	Publish string
+
+	gallery.Gallery
+}
+
+// Galleries resolves extensions across every configured gallery
+//
+// Extensions matching a route resolve from the routed gallery alone. All other
+// extensions are looked up in each gallery in priority order, falling back to
+// the next gallery whenever the extension is not found.
+//
+// Routed extensions deliberately never fall back, so a private extension can
+// never be silently substituted by a public one of the same name.
+type Galleries struct {
+	galleries []NamedGallery
+	routes    []GalleryRoute
+	selected  string
+}
+
+// NewGalleries initializes a client for each gallery in `cfg`
+func NewGalleries(cfg *Config) (*Galleries, error) {
+	profiles := slices.Clone(cfg.Galleries)
+	if cfg.GalleryHost != "" {
+		profiles = append(profiles, GalleryProfile{
+			Name:   defaultGalleryName,
+			Scheme: cfg.GalleryScheme,
+			Host:   cfg.GalleryHost,
+		})
+	}
+	slices.SortStableFunc(profiles, func(a, b GalleryProfile) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	// Every gallery shares a client, bounding requests by the configured timeout
+	client := http.DefaultClient
+	if cfg.RequestTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.RequestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("%w for [request_timeout]: %w", ErrConfigValue, err)
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	self := &Galleries{routes: cfg.Routes, selected: cfg.Gallery}
+	for _, profile := range profiles {
+		if self.lookup(profile.Name) != nil {
+			return nil, fmt.Errorf("gallery [%s] is configured more than once", profile.Name)
+		}
+		scheme := profile.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		g := gallery.New(scheme, profile.Host)
+		g.Client = client
+		self.galleries = append(self.galleries, NamedGallery{
+			Name:    profile.Name,
+			Publish: profile.Publish,
+			Gallery: g,
+		})
+	}
+
+	// Validate references to gallery names
+	if self.selected != "" && self.lookup(self.selected) == nil {
+		return nil, fmt.Errorf("--%s: %w [%s]", flagGallery, ErrUnknownGallery, self.selected)
+	}
+	for _, route := range self.routes {
+		if self.lookup(route.Gallery) == nil {
+			return nil, fmt.Errorf("route [%s]: %w [%s]", route.Pattern, ErrUnknownGallery, route.Gallery)
+		}
+		if _, err := path.Match(route.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("route [%s]: %w", route.Pattern, err)
+		}
+	}
+
+	return self, nil
+}
+
+func (self *Galleries) lookup(name string) *NamedGallery {
+	for i := range self.galleries {
+		if self.galleries[i].Name == name {
+			return &self.galleries[i]
+		}
+	}
+	return nil
+}
+
+// For produces the galleries to try, in order, when resolving extension
+// `pub`.`id`
+func (self *Galleries) For(pub, id string) []NamedGallery {
+	// An explicitly selected gallery is the only option
+	if self.selected != "" {
+		return []NamedGallery{*self.lookup(self.selected)}
+	}
+
+	// Otherwise, the first matching route pins the gallery
+	ext := strings.ToLower(pub + "." + id)
+	for _, route := range self.routes {
+		if ok, _ := path.Match(strings.ToLower(route.Pattern), ext); ok {
+			return []NamedGallery{*self.lookup(route.Gallery)}
+		}
+	}
+
+	return self.galleries
+}
+
+// Primary produces the gallery for operations not tied to a single extension
+// (ex: queries): the selected gallery, if any, or the highest priority
+func (self *Galleries) Primary() (NamedGallery, error) {
+	if self.selected != "" {
+		return *self.lookup(self.selected), nil
+	}
+	if len(self.galleries) == 0 {
+		return NamedGallery{}, ErrNoGallery
+	}
+	return self.galleries[0], nil
+}
+
+func (self *Galleries) Query(ctx context.Context, opts gallery.QueryOptions) iter.Seq2[gallery.ExtensionMeta, error] {
+	primary, err := self.Primary()
+	if err != nil {
+		return func(yield func(gallery.ExtensionMeta, error) bool) {
+			yield(gallery.ExtensionMeta{}, err)
+		}
+	}
+	return primary.Query(ctx, opts)
+}
+
+func (self *Galleries) GetExtension(
+	ctx context.Context,
+	pub, id, ver, targetPlatform string,
+) (gallery.Package, error) {
+	return resolve(self.For(pub, id), func(g NamedGallery) (gallery.Package, error) {
+		return g.GetExtension(ctx, pub, id, ver, targetPlatform)
+	})
+}
+
+// GetResolvedExtension produces the package of `ext` from the gallery its
+// version was resolved from, rather than the first gallery having it
+func (self *Galleries) GetResolvedExtension(ctx context.Context, ext ResolvedExtension) (gallery.Package, error) {
+	galleries := self.For(ext.Publisher, ext.Name)
+	if ext.Gallery != "" {
+		galleries = slices.DeleteFunc(slices.Clone(galleries), func(g NamedGallery) bool {
+			return g.BaseURL.Host != ext.Gallery
+		})
+	}
+	return resolve(galleries, func(g NamedGallery) (gallery.Package, error) {
+		return g.GetExtension(ctx, ext.Publisher, ext.Name, ext.Version, ext.TargetPlatform)
+	})
+}
+
+func (self *Galleries) GetAsset(
+	ctx context.Context,
+	pub, id, ver, targetPlatform string,
+	assetType gallery.AssetType,
+) (gallery.Asset, error) {
+	return resolve(self.For(pub, id), func(g NamedGallery) (gallery.Asset, error) {
+		return g.GetPlatformAsset(ctx, pub, id, ver, targetPlatform, assetType)
+	})
+}
+
+func (self *Galleries) GetExtensionMeta(
+	ctx context.Context,
+	pub, id string,
+) (gallery.ExtensionMeta, error) {
+	return resolve(self.For(pub, id), func(g NamedGallery) (gallery.ExtensionMeta, error) {
+		return g.GetExtensionMeta(ctx, pub, id)
+	})
+}
+
+// GetExtensionVersions produces the versions of extension `pub`.`id`
+// alongside the gallery they were found in
+func (self *Galleries) GetExtensionVersions(
+	ctx context.Context,
+	pub, id string,
+) (gallery.ExtensionMeta, NamedGallery, error) {
+	type found struct {
+		meta gallery.ExtensionMeta
+		from NamedGallery
+	}
+	v, err := resolve(self.For(pub, id), func(g NamedGallery) (found, error) {
+		meta, err := g.GetExtensionVersions(ctx, pub, id)
+		return found{meta, g}, err
+	})
+	return v.meta, v.from, err
+}
+
+// resolve tries `fn` against each of `galleries` in order, moving on to the
+// next only when the extension was not found
+func resolve[T any](galleries []NamedGallery, fn func(g NamedGallery) (T, error)) (T, error) {
+	var zero T
+	if len(galleries) == 0 {
+		return zero, ErrNoGallery
+	}
+
+	var errs []error
+	for _, g := range galleries {
+		v, err := fn(g)
+		if err == nil {
+			return v, nil
+		}
+		errs = append(errs, fmt.Errorf("gallery [%s]: %w", g.Name, err))
+		if !errors.Is(err, gallery.ErrNotFound) {
+			break
+		}
+	}
+
+	return zero, errors.Join(errs...)
+}
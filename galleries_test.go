@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/vsx/gallery"
+	"github.com/illbjorn/zest"
+)
+
+// testGallery starts a gallery serving `body` for every extension request, or
+// a 404 if `body` is empty
+func testGallery(t *testing.T, body string) GalleryProfile {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body == "" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	return GalleryProfile{Scheme: u.Scheme, Host: u.Host}
+}
+
+func TestGalleriesResolve(t *testing.T) {
+	z := zest.New(t)
+
+	missing := testGallery(t, "")
+	missing.Name = "missing"
+	internal := testGallery(t, "internal")
+	internal.Name, internal.Priority = "internal", 1
+	public := testGallery(t, "public")
+	public.Name, public.Priority = "public", 2
+
+	cfg := &Config{
+		Galleries: []GalleryProfile{public, internal, missing},
+		Routes:    []GalleryRoute{{Pattern: "OurCorp.*", Gallery: "missing"}},
+	}
+	g, err := NewGalleries(cfg)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+
+	// Unrouted extensions fall back through galleries in priority order
+	got := testGetExtension(z, g, "usernamehw", "errorlens")
+	z.Assert(got == "internal", "expected [internal], got [%s]", got)
+
+	// Routed extensions never fall back
+	_, err = g.GetExtension(context.Background(), "ourcorp", "tools", "1.0.0", "")
+	z.Assert(errors.Is(err, gallery.ErrNotFound), "expected not found, got [%v]", err)
+
+	// An explicit selection bypasses priority and routes
+	cfg.Gallery = "public"
+	g, err = NewGalleries(cfg)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	got = testGetExtension(z, g, "ourcorp", "tools")
+	z.Assert(got == "public", "expected [public], got [%s]", got)
+
+	// Unknown gallery names are rejected
+	cfg.Gallery = "nope"
+	_, err = NewGalleries(cfg)
+	z.Assert(errors.Is(err, ErrUnknownGallery), "expected unknown gallery, got [%v]", err)
+}
+
+func testGetExtension(z zest.Zester, g *Galleries, pub, id string) string {
+	r, err := g.GetExtension(context.Background(), pub, id, "1.0.0", "")
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	_, _ = io.Copy(&b, r)
+	return b.String()
+}
+
+func TestGetResolvedExtension(t *testing.T) {
+	z := zest.New(t)
+
+	internal := testGallery(t, "internal")
+	internal.Name, internal.Priority = "internal", 1
+	public := testGallery(t, "public")
+	public.Name, public.Priority = "public", 2
+	g, err := NewGalleries(&Config{Galleries: []GalleryProfile{public, internal}})
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+
+	// The package comes from the gallery the version was resolved from, not
+	// the first having it
+	for _, test := range []struct{ gallery, want string }{
+		{public.Host, "public"},
+		{internal.Host, "internal"},
+		{"", "internal"},
+	} {
+		ext := ResolvedExtension{Publisher: "ourcorp", Name: "tools", Version: "1.0.0", Gallery: test.gallery}
+		r, err := g.GetResolvedExtension(context.Background(), ext)
+		z.Assert(err == nil, "[%s]: expected no error, got [%s]", test.gallery, err)
+		if err != nil {
+			continue
+		}
+		var b strings.Builder
+		_, _ = io.Copy(&b, r)
+		z.Assert(b.String() == test.want, "[%s]: expected [%s], got [%s]", test.gallery, test.want, b.String())
+	}
+
+	// Galleries no longer configured aren't substituted
+	ext := ResolvedExtension{Publisher: "ourcorp", Name: "tools", Version: "1.0.0", Gallery: "gone.ourcorp.com"}
+	_, err = g.GetResolvedExtension(context.Background(), ext)
+	z.Assert(errors.Is(err, ErrNoGallery), "expected no gallery, got [%v]", err)
+}
@@ -0,0 +1,162 @@
+package gallery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Asset types of extension versions
+const (
+	AssetVSIXPackage AssetType = "Microsoft.VisualStudio.Services.VSIXPackage"
+	AssetManifest    AssetType = "Microsoft.VisualStudio.Code.Manifest"
+	AssetDetails     AssetType = "Microsoft.VisualStudio.Services.Content.Details"
+	AssetChangelog   AssetType = "Microsoft.VisualStudio.Services.Content.Changelog"
+	AssetLicense     AssetType = "Microsoft.VisualStudio.Services.Content.License"
+	AssetIcon        AssetType = Default
+	AssetIconSmall   AssetType = Small
+)
+
+const (
+	headerChecksumSHA256 = "X-Checksum-Sha256"
+)
+
+// Asset is a fetched extension version asset (ex: its README or changelog)
+type Asset struct {
+	Type AssetType
+
+	// ContentType is the asset's media type as reported by the gallery (ex:
+	// `text/markdown`)
+	ContentType string
+
+	Data []byte
+
+	// SHA256 is the asset's hex-encoded SHA-256 digest as reported by the
+	// gallery, or the empty string if it reported none
+	SHA256 string
+}
+
+// GetAsset fetches the asset of type `assetType` of version `version` of
+// extension `publisherID`.`extensionID`
+func (self Gallery) GetAsset(
+	ctx context.Context,
+	publisherID, extensionID, version string,
+	assetType AssetType,
+) (Asset, error) {
+	return self.GetPlatformAsset(ctx, publisherID, extensionID, version, "", assetType)
+}
+
+// GetManifest fetches the extension's `package.json`
+func (self Gallery) GetManifest(ctx context.Context, publisherID, extensionID, version string) (Asset, error) {
+	return self.GetAsset(ctx, publisherID, extensionID, version, AssetManifest)
+}
+
+// GetDetails fetches the extension's README
+func (self Gallery) GetDetails(ctx context.Context, publisherID, extensionID, version string) (Asset, error) {
+	return self.GetAsset(ctx, publisherID, extensionID, version, AssetDetails)
+}
+
+// GetChangelog fetches the extension's changelog
+func (self Gallery) GetChangelog(ctx context.Context, publisherID, extensionID, version string) (Asset, error) {
+	return self.GetAsset(ctx, publisherID, extensionID, version, AssetChangelog)
+}
+
+// GetLicense fetches the extension's license
+func (self Gallery) GetLicense(ctx context.Context, publisherID, extensionID, version string) (Asset, error) {
+	return self.GetAsset(ctx, publisherID, extensionID, version, AssetLicense)
+}
+
+// GetIcon fetches the extension's icon
+func (self Gallery) GetIcon(ctx context.Context, publisherID, extensionID, version string) (Asset, error) {
+	return self.GetAsset(ctx, publisherID, extensionID, version, AssetIcon)
+}
+
+// GetPlatformAsset is GetAsset for the version published for `targetPlatform`,
+// or the universal version if empty
+//
+// The transfer's progress is reported to any ProgressFunc attached to `ctx`
+// with WithProgress.
+func (self Gallery) GetPlatformAsset(
+	ctx context.Context,
+	publisherID, extensionID, version, targetPlatform string,
+	assetType AssetType,
+) (Asset, error) {
+	const pathFmtGetAsset = "_apis/public/gallery/publisher/" +
+		"%s" /* [1] Publisher ID      */ + "/extension/" +
+		"%s" /* [2] Extension ID      */ + "/" +
+		"%s" /* [3] Extension Version */ + "/assetbyname/" +
+		"%s" /* [4] Asset Type        */
+
+	// Construct the URL
+	path := fmt.Sprintf(pathFmtGetAsset, publisherID, extensionID, version, assetType)
+	url := self.BaseURL.JoinPath(path)
+	if targetPlatform != "" {
+		url.RawQuery = "targetPlatform=" + targetPlatform
+	}
+
+	// Init the HTTP request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return Asset{}, fmt.Errorf("failed to init GET request: %w", err)
+	}
+
+	// Get the response
+	res, err := self.client().Do(req)
+	if err != nil {
+		return Asset{}, fmt.Errorf(
+			"failed to execute GET request to [%s]: %w",
+			url.String(), err,
+		)
+	}
+	defer res.Body.Close()
+
+	// Read the response body, reporting progress if asked to
+	var src io.Reader = res.Body
+	progress := progressFrom(ctx)
+	if progress != nil {
+		src = &progressReader{
+			r:        res.Body,
+			fn:       progress,
+			progress: Progress{Total: res.ContentLength},
+		}
+	}
+	body, err := io.ReadAll(src)
+	if progress != nil {
+		progress(Progress{Read: int64(len(body)), Total: res.ContentLength, Done: true})
+	}
+	if err != nil {
+		return Asset{}, fmt.Errorf("failed to read asset response body: %w", err)
+	}
+
+	// Evaluate request failures
+	//
+	// We include the response body in the error message if the status code is
+	// >= 400 (hence this conditional being >1 step from the actual doing of the
+	// request)
+	if res.StatusCode >= http.StatusBadRequest {
+		// If the response body is large, truncate it
+		if len(body) > 100 {
+			body = append(body[:97], '.', '.', '.')
+		}
+		// Distinguish missing extensions (and assets) so callers may try
+		// elsewhere
+		if res.StatusCode == http.StatusNotFound {
+			return Asset{}, fmt.Errorf(
+				"%w: received HTTP status code [%d] in GET request to [%s]: %s",
+				ErrNotFound, res.StatusCode, url.String(), string(body),
+			)
+		}
+		return Asset{}, fmt.Errorf(
+			"received received HTTP status code [%d] in GET request to [%s]: %s",
+			res.StatusCode, url.String(), string(body),
+		)
+	}
+
+	return Asset{
+		Type:        assetType,
+		ContentType: res.Header.Get("Content-Type"),
+		Data:        body,
+		SHA256:      res.Header.Get(headerChecksumSHA256),
+	}, nil
+}
@@ -1,6 +1,10 @@
 package gallery
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type ExtensionQueryResponse struct {
 	Results     []ExtensionQueryResult `json:"results"`
@@ -12,37 +16,254 @@ type ExtensionQueryResult struct {
 }
 
 type ExtensionMeta struct {
-	Publisher   Publisher   `json:"publisher"`
-	ID          string      `json:"id"`
-	Name        string      `json:"extensionName"`
-	DisplayName string      `json:"displayName"`
-	Flags       string      `json:"flags"`
-	LastUpdated time.Time   `json:"lastUpdated"`
-	Published   time.Time   `json:"published"`
-	Description string      `json:"description"`
-	Versions    []Version   `json:"versions"`
-	Statistics  []Statistic `json:"statistics"`
+	Publisher   Publisher          `json:"publisher"`
+	ID          string             `json:"id"`
+	Name        string             `json:"extensionName"`
+	DisplayName string             `json:"displayName"`
+	Flags       ExtensionMetaFlags `json:"flags"`
+	LastUpdated time.Time          `json:"lastUpdated"`
+	Published   time.Time          `json:"published"`
+	Description string             `json:"description"`
+	Versions    []Version          `json:"versions"`
+	Statistics  Statistics         `json:"statistics"`
 }
 
 type Publisher struct {
-	ID          string `json:"publisherId"`
-	Name        string `json:"publisherName"`
-	DisplayName string `json:"displayName"`
-	Flags       string `json:"flags"`
-	Domain      string `json:"domain"`
+	ID             string         `json:"publisherId"`
+	Name           string         `json:"publisherName"`
+	DisplayName    string         `json:"displayName"`
+	Flags          PublisherFlags `json:"flags"`
+	Domain         string         `json:"domain"`
+	DomainVerified bool           `json:"isDomainVerified"`
 }
 
 type Version struct {
-	Version    string    `json:"version"`
-	Flags      string    `json:"flags"`
-	LastUpated time.Time `json:"lastUpdated"`
+	Version          string       `json:"version"`
+	Flags            VersionFlags `json:"flags"`
+	LastUpated       time.Time    `json:"lastUpdated"`
+	TargetPlatform   string       `json:"targetPlatform"`
+	Properties       []Property   `json:"properties"`
+	Files            []File       `json:"files"`
+	AssetURI         string       `json:"assetUri"`
+	FallbackAssetURI string       `json:"fallbackAssetUri"`
+}
+
+// Property returns the value of the version property `key`, if present
+func (self Version) Property(key string) (string, bool) {
+	for _, p := range self.Properties {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// Version property keys
+const (
+	// PropertyEngine is the range of editor versions the version supports (ex:
+	// `^1.80.0`)
+	PropertyEngine = "Microsoft.VisualStudio.Code.Engine"
+
+	// PropertyPreRelease is `true` for pre-release versions
+	PropertyPreRelease = "Microsoft.VisualStudio.Code.PreRelease"
+)
+
+// Engine returns the range of editor versions the version supports, if
+// declared
+func (self Version) Engine() string {
+	engine, _ := self.Property(PropertyEngine)
+	return engine
+}
+
+// PreRelease reports whether the version is a pre-release
+func (self Version) PreRelease() bool {
+	v, _ := self.Property(PropertyPreRelease)
+	return strings.EqualFold(v, "true")
+}
+
+// File returns the source URI of the version asset of type `assetType`, if
+// present
+func (self Version) File(assetType AssetType) (string, bool) {
+	for _, f := range self.Files {
+		if f.AssetType == assetType {
+			return f.Source, true
+		}
+	}
+	return "", false
+}
+
+// Property is a single key/value pair from a version's `properties` (ex: the
+// supported engine range or pre-release status)
+type Property struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// File is a single asset associated with a version (ex: the manifest, README
+// or icon)
+type File struct {
+	AssetType AssetType `json:"assetType"`
+	Source    string    `json:"source"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Flags
+//
+// The gallery represents flags as a single comma-separated string (ex:
+// `validated, public, preview`). These are decoded into booleans here.
+
+// ExtensionMetaFlags are the decoded flags of an ExtensionMeta
+type ExtensionMetaFlags struct {
+	Validated bool
+	Public    bool
+	Preview   bool
+	Trusted   bool
+}
+
+func (self *ExtensionMetaFlags) UnmarshalJSON(data []byte) error {
+	flags, err := decodeFlags(data)
+	if err != nil {
+		return err
+	}
+	self.Validated = flags["validated"]
+	self.Public = flags["public"]
+	self.Preview = flags["preview"]
+	self.Trusted = flags["trusted"]
+	return nil
+}
+
+func (self ExtensionMetaFlags) MarshalJSON() ([]byte, error) {
+	return encodeFlags(map[string]bool{
+		"validated": self.Validated,
+		"public":    self.Public,
+		"preview":   self.Preview,
+		"trusted":   self.Trusted,
+	})
+}
+
+// PublisherFlags are the decoded flags of a Publisher
+type PublisherFlags struct {
+	Verified bool
+}
+
+func (self *PublisherFlags) UnmarshalJSON(data []byte) error {
+	flags, err := decodeFlags(data)
+	if err != nil {
+		return err
+	}
+	self.Verified = flags["verified"]
+	return nil
+}
+
+func (self PublisherFlags) MarshalJSON() ([]byte, error) {
+	return encodeFlags(map[string]bool{
+		"verified": self.Verified,
+	})
+}
+
+// VersionFlags are the decoded flags of a Version
+type VersionFlags struct {
+	Validated bool
+}
+
+func (self *VersionFlags) UnmarshalJSON(data []byte) error {
+	flags, err := decodeFlags(data)
+	if err != nil {
+		return err
+	}
+	self.Validated = flags["validated"]
+	return nil
 }
 
+func (self VersionFlags) MarshalJSON() ([]byte, error) {
+	return encodeFlags(map[string]bool{
+		"validated": self.Validated,
+	})
+}
+
+// decodeFlags splits a JSON-encoded, comma-separated flag string into the set
+// of (lower-cased) flags it contains
+func decodeFlags(data []byte) (map[string]bool, error) {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]bool)
+	for flag := range strings.SplitSeq(raw, ",") {
+		if flag = strings.ToLower(strings.TrimSpace(flag)); flag != "" {
+			flags[flag] = true
+		}
+	}
+
+	return flags, nil
+}
+
+// encodeFlags produces the gallery's comma-separated representation of the set
+// flags in `flags`
+func encodeFlags(flags map[string]bool) ([]byte, error) {
+	var set []string
+	for _, flag := range [...]string{"validated", "public", "preview", "trusted", "verified"} {
+		if flags[flag] {
+			set = append(set, flag)
+		}
+	}
+	return json.Marshal(strings.Join(set, ", "))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Statistics
+
 type Statistic struct {
-	Kind  string
-	Value float64
+	Kind  string  `json:"statisticName"`
+	Value float64 `json:"value"`
 }
 
 const (
-	StatisticKindInstall = "install"
+	StatisticKindInstall         = "install"
+	StatisticKindAverageRating   = "averagerating"
+	StatisticKindRatingCount     = "ratingcount"
+	StatisticKindTrendingDaily   = "trendingdaily"
+	StatisticKindTrendingWeekly  = "trendingweekly"
+	StatisticKindTrendingMonthly = "trendingmonthly"
 )
+
+// Statistics is the set of statistics reported for an extension
+//
+// Galleries are free to omit any statistic, in which case the accessors below
+// produce zero.
+type Statistics []Statistic
+
+// Get returns the value of the statistic of kind `kind`, if present
+func (self Statistics) Get(kind string) (float64, bool) {
+	for _, stat := range self {
+		if strings.EqualFold(stat.Kind, kind) {
+			return stat.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Installs is the extension's install count
+func (self Statistics) Installs() float64 {
+	v, _ := self.Get(StatisticKindInstall)
+	return v
+}
+
+// AverageRating is the extension's average rating (0-5)
+func (self Statistics) AverageRating() float64 {
+	v, _ := self.Get(StatisticKindAverageRating)
+	return v
+}
+
+// RatingCount is the number of ratings the extension has received
+func (self Statistics) RatingCount() float64 {
+	v, _ := self.Get(StatisticKindRatingCount)
+	return v
+}
+
+// Trending is the extension's weekly trending score
+func (self Statistics) Trending() float64 {
+	v, _ := self.Get(StatisticKindTrendingWeekly)
+	return v
+}
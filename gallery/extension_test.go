@@ -0,0 +1,70 @@
+package gallery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+const testExtensionMeta = `{
+  "publisher": {
+    "publisherName": "usernamehw",
+    "displayName": "Alexander",
+    "flags": "verified",
+    "isDomainVerified": true
+  },
+  "extensionName": "errorlens",
+  "flags": "validated, public, preview",
+  "versions": [
+    {
+      "version": "3.26.0",
+      "flags": "validated",
+      "targetPlatform": "linux-x64",
+      "properties": [
+        { "key": "Microsoft.VisualStudio.Code.Engine", "value": "^1.80.0" }
+      ],
+      "files": [
+        { "assetType": "Microsoft.VisualStudio.Code.Manifest", "source": "https://example.com/manifest" }
+      ]
+    }
+  ],
+  "statistics": [
+    { "statisticName": "averagerating", "value": 4.5 },
+    { "statisticName": "install", "value": 1234 },
+    { "statisticName": "ratingcount", "value": 10 },
+    { "statisticName": "trendingweekly", "value": 0.25 }
+  ]
+}`
+
+func TestDecodeExtensionMeta(t *testing.T) {
+	z := zest.New(t)
+
+	var meta ExtensionMeta
+	err := json.Unmarshal([]byte(testExtensionMeta), &meta)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+
+	// Flags
+	z.Assert(meta.Flags.Validated, "expected extension to be validated")
+	z.Assert(meta.Flags.Public, "expected extension to be public")
+	z.Assert(meta.Flags.Preview, "expected extension to be preview")
+	z.Assert(!meta.Flags.Trusted, "expected extension to not be trusted")
+	z.Assert(meta.Publisher.Flags.Verified, "expected publisher to be verified")
+	z.Assert(meta.Publisher.DomainVerified, "expected publisher domain to be verified")
+
+	// Statistics
+	z.Assert(meta.Statistics.Installs() == 1234, "expected [1234] installs, got [%f]", meta.Statistics.Installs())
+	z.Assert(meta.Statistics.AverageRating() == 4.5, "expected [4.5] rating, got [%f]", meta.Statistics.AverageRating())
+	z.Assert(meta.Statistics.RatingCount() == 10, "expected [10] ratings, got [%f]", meta.Statistics.RatingCount())
+	z.Assert(meta.Statistics.Trending() == 0.25, "expected [0.25] trending, got [%f]", meta.Statistics.Trending())
+
+	// Versions
+	z.Assert(len(meta.Versions) == 1, "expected [1] version, got [%d]", len(meta.Versions))
+	ver := meta.Versions[0]
+	z.Assert(ver.Flags.Validated, "expected version to be validated")
+	z.Assert(ver.TargetPlatform == "linux-x64", "expected target platform [linux-x64], got [%s]", ver.TargetPlatform)
+	engine, ok := ver.Property("Microsoft.VisualStudio.Code.Engine")
+	z.Assert(ok && engine == "^1.80.0", "expected engine [^1.80.0], got [%s]", engine)
+	manifest, ok := ver.File("Microsoft.VisualStudio.Code.Manifest")
+	z.Assert(ok && manifest == "https://example.com/manifest", "expected manifest URI, got [%s]", manifest)
+}
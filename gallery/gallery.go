@@ -21,5 +21,15 @@ type Gallery struct {
 	BaseURL *url.URL
 
 	// Client is a standard HTTP client with a not-forever timeout applied
+	//
+	// If nil, `http.DefaultClient` is used.
 	Client *http.Client
 }
+
+// client produces the HTTP client for gallery requests
+func (self Gallery) client() *http.Client {
+	if self.Client == nil {
+		return http.DefaultClient
+	}
+	return self.Client
+}
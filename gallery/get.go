@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net/http"
 )
 
 type VoltronReader interface {
@@ -17,67 +16,92 @@ type VoltronReader interface {
 	Size() int64
 }
 
-// GetExtension accepts a gallery publisherID, extension ID and version
-// returning a `VoltronReader` capable of being wrapped into a `zip.Reader`.
-func (self Gallery) GetExtension(
-	ctx context.Context,
-	publisherID, extensionID, version string,
-) (VoltronReader, error) {
-	const assetKindVSIXPackage = "Microsoft.VisualStudio.Services.VSIXPackage"
-	const pathFmtGetExtension = "_apis/public/gallery/publisher/" +
-		"%s" /* [1] Publisher ID      */ + "/extension/" +
-		"%s" /* [2] Extension ID      */ + "/" +
-		"%s" /* [3] Extension Version */ + "/assetbyname/" + assetKindVSIXPackage
+// Package is a fetched extension package
+type Package struct {
+	VoltronReader
 
-	// Construct the URL
-	path := fmt.Sprintf(pathFmtGetExtension, publisherID, extensionID, version)
-	url := self.BaseURL.JoinPath(path)
+	// SHA256 is the package's hex-encoded SHA-256 digest as reported by the
+	// gallery, or the empty string if it reported none
+	//
+	// The Visual Studio Marketplace reports none, though gallery proxies such as
+	// Artifactory and Nexus do (the `X-Checksum-Sha256` response header).
+	SHA256 string
+}
 
-	// Init the HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+// GetExtension accepts a gallery publisherID, extension ID, version and
+// (optional) target platform returning a `Package` capable of being wrapped
+// into a `zip.Reader`.
+//
+// The transfer's progress is reported to any ProgressFunc attached to `ctx`
+// with WithProgress.
+func (self Gallery) GetExtension(
+	ctx context.Context,
+	publisherID, extensionID, version, targetPlatform string,
+) (Package, error) {
+	asset, err := self.GetPlatformAsset(ctx, publisherID, extensionID, version, targetPlatform, AssetVSIXPackage)
 	if err != nil {
-		return nil, fmt.Errorf("failed to init GET request: %w", err)
+		return Package{}, err
 	}
 
-	// Get the response
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to execute GET request to [%s]: %w",
-			url.String(), err,
-		)
-	}
-	defer res.Body.Close()
+	// Wrap into `bytes.Reader` which implements `io.ReaderAt`
+	r := bytes.NewReader(asset.Data)
 
-	// The response body is the VSIX package, we need this as an `io.ReaderAt`
-	// to pass it to `zip.NewReader()`. To achieve this we read the response body
-	// and wrap it back into a `bytes.Reader`.
+	// https://i.imgflip.com/5g7vmt.jpg
+	return Package{
+		VoltronReader: r,
+		SHA256:        asset.SHA256,
+	}, nil
+}
 
-	// Read the response body
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read extension response body: %w", err)
+var (
+	ErrNotFound = fmt.Errorf("extension not found")
+)
+
+// GetExtensionMeta accepts a gallery publisherID and extension ID returning the
+// extension's metadata, including its latest version and statistics.
+func (self Gallery) GetExtensionMeta(
+	ctx context.Context,
+	publisherID, extensionID string,
+) (ExtensionMeta, error) {
+	return self.getExtensionMeta(ctx, publisherID, extensionID, QueryRequestFlagsDefault)
+}
+
+// GetExtensionVersions accepts a gallery publisherID and extension ID returning
+// the extension's metadata including every published version, with each
+// version's properties (ex: engine, pre-release), newest first.
+func (self Gallery) GetExtensionVersions(
+	ctx context.Context,
+	publisherID, extensionID string,
+) (ExtensionMeta, error) {
+	const flags = QueryRequestFlagIncludeVersions |
+		QueryRequestFlagIncludeVersionProperties |
+		QueryRequestFlagIncludeFiles |
+		QueryRequestFlagIncludeAssetURI |
+		QueryRequestFlagIncludeInstallationTargets |
+		QueryRequestFlagExcludeNonValidated
+	return self.getExtensionMeta(ctx, publisherID, extensionID, flags)
+}
+
+func (self Gallery) getExtensionMeta(
+	ctx context.Context,
+	publisherID, extensionID string,
+	flags QueryRequestFlags,
+) (ExtensionMeta, error) {
+	opts := QueryOptions{
+		ExtensionName: publisherID + "." + extensionID,
+		Limit:         1,
+		Flags:         flags,
 	}
 
-	// Evaluate request failures
-	//
-	// We include the response body in the error message if the status code is
-	// >= 400 (hence this conditional being >1 step from the actual doing of the
-	// request)
-	if res.StatusCode >= http.StatusBadRequest {
-		// If the response body is large, truncate it
-		if len(body) > 100 {
-			body = append(body[:97], '.', '.', '.')
+	for meta, err := range self.Query(ctx, opts) {
+		if err != nil {
+			return ExtensionMeta{}, err
 		}
-		return nil, fmt.Errorf(
-			"received received HTTP status code [%d] in GET request to [%s]: %s",
-			res.StatusCode, url.String(), string(body),
-		)
+		return meta, nil
 	}
 
-	// Wrap into `bytes.Reader` which implements `io.ReaderAt`
-	r := bytes.NewReader(body)
-
-	// https://i.imgflip.com/5g7vmt.jpg
-	return r, nil
+	return ExtensionMeta{}, fmt.Errorf(
+		"%w: [%s.%s]",
+		ErrNotFound, publisherID, extensionID,
+	)
 }
@@ -0,0 +1,54 @@
+package gallery
+
+import (
+	"context"
+	"io"
+)
+
+// Progress describes the state of an extension package transfer
+type Progress struct {
+	// Read is the number of bytes received so far
+	Read int64
+
+	// Total is the expected number of bytes, or -1 if unknown
+	Total int64
+
+	// Done reports whether the transfer has ended, successfully or not
+	Done bool
+}
+
+// ProgressFunc receives progress updates for a single transfer
+//
+// It's called from the goroutine performing the transfer, as each chunk of the
+// response is read.
+type ProgressFunc func(p Progress)
+
+type progressKey struct{}
+
+// WithProgress produces a context reporting the progress of package transfers
+// made with it (ex: GetExtension) to `fn`
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressFrom produces the ProgressFunc attached to `ctx`, if any
+func progressFrom(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// progressReader reports the bytes read through it to a ProgressFunc
+type progressReader struct {
+	r        io.Reader
+	fn       ProgressFunc
+	progress Progress
+}
+
+func (self *progressReader) Read(p []byte) (int, error) {
+	n, err := self.r.Read(p)
+	if n > 0 {
+		self.progress.Read += int64(n)
+		self.fn(self.progress)
+	}
+	return n, err
+}
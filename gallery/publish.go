@@ -0,0 +1,128 @@
+package gallery
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// PublishAPI is a gallery's API for publishing extensions
+type PublishAPI = string
+
+const (
+	// PublishOpenVSX is the Open VSX registry's publish API (`POST
+	// /api/-/publish`)
+	PublishOpenVSX PublishAPI = "openvsx"
+
+	// PublishMarketplace is the Visual Studio Marketplace's publish API, as
+	// implemented by compatible galleries
+	PublishMarketplace PublishAPI = "marketplace"
+)
+
+const (
+	// marketplaceAPIVersion is the version of the Marketplace API requested
+	marketplaceAPIVersion = "7.1-preview.1"
+)
+
+var (
+	ErrPublishAPI   = fmt.Errorf("unknown publish API, expected 'openvsx' or 'marketplace'")
+	ErrUnauthorized = fmt.Errorf("not authorized to publish")
+)
+
+// Publish uploads VSIX package `vsix` of extension `publisherID`.`extensionID`
+// with publish API `api`, authenticating with access token `token`
+//
+// Marketplace extensions are updated, or created if not yet published.
+func (self Gallery) Publish(
+	ctx context.Context,
+	api PublishAPI,
+	token, publisherID, extensionID string,
+	vsix []byte,
+) error {
+	switch api {
+	case PublishOpenVSX:
+		u := self.BaseURL.JoinPath("api/-/publish")
+		u.RawQuery = "token=" + url.QueryEscape(token)
+		return self.upload(ctx, http.MethodPost, u, nil, vsix)
+
+	case PublishMarketplace:
+		const pathFmtUpdate = "_apis/gallery/publishers/" +
+			"%s" /* [1] Publisher ID */ + "/extensions/" +
+			"%s" /* [2] Extension ID */
+
+		header := http.Header{}
+		header.Set("Accept", "application/json;api-version="+marketplaceAPIVersion)
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(":"+token)))
+
+		update := self.BaseURL.JoinPath(fmt.Sprintf(pathFmtUpdate, publisherID, extensionID))
+		err := self.upload(ctx, http.MethodPut, update, header, vsix)
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return self.upload(ctx, http.MethodPost, self.BaseURL.JoinPath("_apis/gallery/extensions"), header, vsix)
+
+	default:
+		return fmt.Errorf("%w, got [%s]", ErrPublishAPI, api)
+	}
+}
+
+// upload sends `vsix` to `u` with `method` and the headers of `header`
+func (self Gallery) upload(ctx context.Context, method string, u *url.URL, header http.Header, vsix []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(vsix))
+	if err != nil {
+		return fmt.Errorf("failed to init %s request: %w", method, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("user-agent", userAgent)
+
+	res, err := self.client().Do(req)
+	if err != nil {
+		// Never leak the token in the query, which `url.Error` repeats
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to execute %s request to [%s]: %w", method, redact(u), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 100))
+		if len(body) == 100 {
+			body = append(body[:97], '.', '.', '.')
+		}
+		switch res.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf(
+				"%w: received HTTP status code [%d] in %s request to [%s]: %s",
+				ErrUnauthorized, res.StatusCode, method, redact(u), string(body),
+			)
+		case http.StatusNotFound:
+			return fmt.Errorf(
+				"%w: received HTTP status code [%d] in %s request to [%s]: %s",
+				ErrNotFound, res.StatusCode, method, redact(u), string(body),
+			)
+		}
+		return fmt.Errorf(
+			"received HTTP status code [%d] in %s request to [%s]: %s",
+			res.StatusCode, method, redact(u), string(body),
+		)
+	}
+
+	return nil
+}
+
+// redact produces `u` without its query, which may hold credentials
+func redact(u *url.URL) string {
+	redacted := *u
+	redacted.RawQuery = ""
+	return redacted.String()
+}
@@ -0,0 +1,62 @@
+package gallery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+func TestPublish(t *testing.T) {
+	z := zest.New(t)
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+
+		switch {
+		case r.URL.Query().Get("token") == "bad", r.Header.Get("Authorization") == "Basic OmJhZA==":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/new/"):
+			http.NotFound(w, r)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	g := New(u.Scheme, u.Host)
+	ctx := context.Background()
+
+	// Open VSX
+	err := g.Publish(ctx, PublishOpenVSX, "secret", "ourcorp", "tools", []byte("vsix"))
+	z.Assert(err == nil, "unexpected error: %s", err)
+
+	// Marketplace, updating then creating
+	err = g.Publish(ctx, PublishMarketplace, "secret", "ourcorp", "tools", []byte("vsix"))
+	z.Assert(err == nil, "unexpected error: %s", err)
+	err = g.Publish(ctx, PublishMarketplace, "secret", "new", "tools", []byte("vsix"))
+	z.Assert(err == nil, "unexpected error: %s", err)
+
+	want := []string{
+		"POST /api/-/publish vsix",
+		"PUT /_apis/gallery/publishers/ourcorp/extensions/tools vsix",
+		"PUT /_apis/gallery/publishers/new/extensions/tools vsix",
+		"POST /_apis/gallery/extensions vsix",
+	}
+	z.Assert(strings.Join(requests, "\n") == strings.Join(want, "\n"), "expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(requests, "\n"))
+
+	// Rejected tokens, which are never repeated
+	err = g.Publish(ctx, PublishOpenVSX, "bad", "ourcorp", "tools", []byte("vsix"))
+	z.Assert(errors.Is(err, ErrUnauthorized), "expected unauthorized, got [%v]", err)
+	z.Assert(!strings.Contains(err.Error(), "token="), "expected a redacted URL, got [%s]", err)
+	err = g.Publish(ctx, PublishMarketplace, "bad", "ourcorp", "tools", []byte("vsix"))
+	z.Assert(errors.Is(err, ErrUnauthorized), "expected unauthorized, got [%v]", err)
+}
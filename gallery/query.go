@@ -8,9 +8,54 @@ import (
 	"io"
 	"iter"
 	"net/http"
+	"strconv"
 )
 
-func (self Gallery) Query(ctx context.Context, term string) iter.Seq2[ExtensionMeta, error] {
+// QueryOptions describes a single extension query.
+//
+// Any field left at its zero value is excluded from the request.
+type QueryOptions struct {
+	// Term is the free-text search term
+	Term string
+
+	// Category restricts results to a single gallery category (ex: `Themes`)
+	Category string
+
+	// Tags restricts results to extensions carrying any of the provided tags
+	Tags []string
+
+	// Publisher restricts results to a single publisher name
+	Publisher string
+
+	// ExtensionName restricts results to a single exact extension identifier in
+	// `publisher.name` form
+	ExtensionName string
+
+	// TargetPlatform restricts results to extensions published for a given
+	// platform (ex: `linux-x64`, `darwin-arm64`)
+	TargetPlatform string
+
+	// SortBy and SortOrder define the ordering of results
+	SortBy    SortBy
+	SortOrder SortOrder
+
+	// PageSize is the number of results requested per page
+	//
+	// Default: 20
+	PageSize uint16
+
+	// Limit caps the total number of results yielded
+	//
+	// If Limit is zero, only the first page of results is yielded.
+	Limit int
+
+	// Flags selects the optional data included with each result
+	//
+	// Default: QueryRequestFlagsDefault
+	Flags QueryRequestFlags
+}
+
+func (self Gallery) Query(ctx context.Context, opts QueryOptions) iter.Seq2[ExtensionMeta, error] {
 	return func(yield func(ExtensionMeta, error) bool) {
 		const path = "/_apis/public/gallery/extensionquery"
 
@@ -19,20 +64,27 @@ func (self Gallery) Query(ctx context.Context, term string) iter.Seq2[ExtensionM
 
 		// Construct the extension query request
 		queryRequest := defaultQueryRequest()
+		filter := &queryRequest.Filters[0]
 
-		// Append the filter
+		// Append the filters
 		//
 		// Seriously, this API design is.. Rough to work with.
-		queryRequest.Filters[0].Criteria = append(queryRequest.Filters[0].Criteria, QueryFilterCriteria{
-			FilterType: QueryFilterTypeTerm,
-			Value:      term,
-		})
+		filter.Criteria = append(filter.Criteria, opts.criteria()...)
+		filter.SortBy = opts.SortBy
+		filter.SortOrder = opts.SortOrder
+		if opts.PageSize > 0 {
+			filter.PageSize = opts.PageSize
+		}
+		if opts.Flags != 0 {
+			queryRequest.Flags = opts.Flags
+		}
 
+		yielded := 0
 		nextToken := ""
 		for {
 			// Insert the paging token, if we have one from a previous iteration
 			if nextToken != "" {
-				queryRequest.Filters[0].PagingToken = nextToken
+				filter.PagingToken = nextToken
 			}
 
 			// JSON-encode the query request
@@ -55,7 +107,7 @@ func (self Gallery) Query(ctx context.Context, term string) iter.Seq2[ExtensionM
 			req.Header.Set("content-type", "application/json; charset=utf-8")
 
 			// Get the response
-			res, err := http.DefaultClient.Do(req)
+			res, err := self.client().Do(req)
 			if err != nil {
 				yield(ExtensionMeta{}, fmt.Errorf(
 					"failed to execute POST request to [%s]: %w",
@@ -101,26 +153,75 @@ func (self Gallery) Query(ctx context.Context, term string) iter.Seq2[ExtensionM
 			}
 
 			// Ship results as we receive them
+			received := 0
 			for _, result := range queryResponse.Results {
 				for _, extension := range result.Extensions {
+					received++
 					if !yield(extension, nil) {
 						return
 					}
+					yielded++
+					if opts.Limit > 0 && yielded >= opts.Limit {
+						return
+					}
 				}
 			}
 
-			if queryResponse.PagingToken == "" {
-				// If we're out of paginated results, return
+			// Queries without a limit yield the first page alone
+			if opts.Limit == 0 {
 				return
+			}
 
-			} else {
-				// Otherwise, get the next page's results
+			if queryResponse.PagingToken != "" {
+				// If the gallery handed us a paging token, use it to get the next
+				// page's results
 				nextToken = queryResponse.PagingToken
+
+			} else if nextToken == "" && received == int(filter.PageSize) {
+				// Otherwise, if the gallery doesn't page by token and this page was
+				// full, request the next page by number
+				filter.PageNumber++
+
+			} else {
+				// If we're out of paginated results, return
+				return
 			}
 		}
 	}
 }
 
+// Empty reports whether the QueryOptions hold neither a search term nor any
+// filter
+func (self QueryOptions) Empty() bool {
+	return len(self.criteria()) == 0
+}
+
+// criteria produces the filter criteria corresponding to each non-zero field of
+// the QueryOptions
+func (self QueryOptions) criteria() []QueryFilterCriteria {
+	var criteria []QueryFilterCriteria
+	add := func(filterType QueryFilterType, value string) {
+		if value == "" {
+			return
+		}
+		criteria = append(criteria, QueryFilterCriteria{
+			FilterType: filterType,
+			Value:      value,
+		})
+	}
+
+	add(QueryFilterTypeTerm, self.Term)
+	add(QueryFilterTypeCategory, self.Category)
+	for _, tag := range self.Tags {
+		add(QueryFilterTypeTag, tag)
+	}
+	add(QueryFilterTypePublisherName, self.Publisher)
+	add(QueryFilterTypeExtensionName, self.ExtensionName)
+	add(QueryFilterTypeTargetPlatform, self.TargetPlatform)
+
+	return criteria
+}
+
 func defaultQueryRequest() QueryRequest {
 	// Just add a defaultQueryRequest.Filters[0].Criteria of type Term containing
 	// a value of the search term
@@ -138,15 +239,15 @@ func defaultQueryRequest() QueryRequest {
 						Value:      "Microsoft.VisualStudio.Code",
 					},
 					{
-						FilterType: QueryFilterTypeIDK,
-						Value:      "37888",
+						FilterType: QueryFilterTypeExcludeWithFlags,
+						Value:      ExtensionFlagsExcludeDefault.String(),
 					},
 				},
 				Direction:  2,
 				PageNumber: 1,
 				PageSize:   20,
-				SortBy:     0,
-				SortOrder:  0,
+				SortBy:     SortByRelevance,
+				SortOrder:  SortOrderDefault,
 			},
 		},
 		Flags: QueryRequestFlagsDefault,
@@ -167,19 +268,60 @@ type QueryRequest struct {
 	Flags      QueryRequestFlags
 }
 
-type QueryRequestFlags uint16
+// QueryRequestFlags control which optional data the gallery includes with each
+// extension in a query response
+type QueryRequestFlags uint32
 
 const (
-	QueryRequestFlagsDefault QueryRequestFlags = 870
+	QueryRequestFlagIncludeVersions            QueryRequestFlags = 0x1
+	QueryRequestFlagIncludeFiles               QueryRequestFlags = 0x2
+	QueryRequestFlagIncludeCategoryAndTags     QueryRequestFlags = 0x4
+	QueryRequestFlagIncludeSharedAccounts      QueryRequestFlags = 0x8
+	QueryRequestFlagIncludeVersionProperties   QueryRequestFlags = 0x10
+	QueryRequestFlagExcludeNonValidated        QueryRequestFlags = 0x20
+	QueryRequestFlagIncludeInstallationTargets QueryRequestFlags = 0x40
+	QueryRequestFlagIncludeAssetURI            QueryRequestFlags = 0x80
+	QueryRequestFlagIncludeStatistics          QueryRequestFlags = 0x100
+	QueryRequestFlagIncludeLatestVersionOnly   QueryRequestFlags = 0x200
+	QueryRequestFlagUnpublished                QueryRequestFlags = 0x1000
+
+	// QueryRequestFlagsDefault requests the latest version only, along with its
+	// files, statistics, installation targets, categories and tags (870)
+	QueryRequestFlagsDefault = QueryRequestFlagIncludeLatestVersionOnly |
+		QueryRequestFlagIncludeStatistics |
+		QueryRequestFlagIncludeInstallationTargets |
+		QueryRequestFlagExcludeNonValidated |
+		QueryRequestFlagIncludeCategoryAndTags |
+		QueryRequestFlagIncludeFiles
 )
 
+// ExtensionFlags are the gallery's published extension flags, used here as the
+// value of a QueryFilterTypeExcludeWithFlags criterion
+type ExtensionFlags uint32
+
+const (
+	ExtensionFlagSystem      ExtensionFlags = 0x400
+	ExtensionFlagUnpublished ExtensionFlags = 0x1000
+	ExtensionFlagHidden      ExtensionFlags = 0x8000
+
+	// ExtensionFlagsExcludeDefault excludes system, unpublished and hidden
+	// extensions from query results (37888)
+	ExtensionFlagsExcludeDefault = ExtensionFlagSystem |
+		ExtensionFlagUnpublished |
+		ExtensionFlagHidden
+)
+
+func (self ExtensionFlags) String() string {
+	return strconv.FormatUint(uint64(self), 10)
+}
+
 type QueryFilter struct {
 	Criteria    []QueryFilterCriteria `json:"criteria"`
 	Direction   uint8                 `json:"direction"`
 	PageNumber  uint16                `json:"pageNumber"`
 	PageSize    uint16                `json:"pageSize"`
-	SortBy      uint8                 `json:"sortBy"`
-	SortOrder   uint8                 `json:"sortOrder"`
+	SortBy      SortBy                `json:"sortBy"`
+	SortOrder   SortOrder             `json:"sortOrder"`
 	PagingToken string                `json:"pagingToken"`
 }
 
@@ -191,7 +333,37 @@ type QueryFilterCriteria struct {
 type QueryFilterType uint8
 
 const (
-	QueryFilterTypeTerm    QueryFilterType = 10
-	QueryFilterTypeProduct QueryFilterType = 8
-	QueryFilterTypeIDK     QueryFilterType = 12
+	QueryFilterTypeTag              QueryFilterType = 1
+	QueryFilterTypeExtensionID      QueryFilterType = 4
+	QueryFilterTypeCategory         QueryFilterType = 5
+	QueryFilterTypeExtensionName    QueryFilterType = 7
+	QueryFilterTypeProduct          QueryFilterType = 8
+	QueryFilterTypeFeatured         QueryFilterType = 9
+	QueryFilterTypeTerm             QueryFilterType = 10
+	QueryFilterTypeExcludeWithFlags QueryFilterType = 12
+	QueryFilterTypePublisherName    QueryFilterType = 18
+	QueryFilterTypeTargetPlatform   QueryFilterType = 23
+)
+
+// SortBy selects the field by which query results are ordered
+type SortBy uint8
+
+const (
+	SortByRelevance      SortBy = 0
+	SortByUpdated        SortBy = 1
+	SortByName           SortBy = 2
+	SortByPublisher      SortBy = 3
+	SortByInstalls       SortBy = 4
+	SortByRating         SortBy = 6
+	SortByPublished      SortBy = 10
+	SortByWeightedRating SortBy = 12
+)
+
+// SortOrder selects the direction in which query results are ordered
+type SortOrder uint8
+
+const (
+	SortOrderDefault    SortOrder = 0
+	SortOrderAscending  SortOrder = 1
+	SortOrderDescending SortOrder = 2
 )
@@ -0,0 +1,64 @@
+package gallery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+// testPagingGallery serves 3 pages of 2 extensions, each page but the last
+// handing out a paging token to the next, recording the tokens received
+func testPagingGallery(t *testing.T, tokens *[]string) Gallery {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query QueryRequest
+		_ = json.NewDecoder(r.Body).Decode(&query)
+		token := query.Filters[0].PagingToken
+		*tokens = append(*tokens, token)
+
+		page := len(*tokens)
+		next := ""
+		if page < 3 {
+			next = fmt.Sprintf("page-%d", page+1)
+		}
+		_, _ = fmt.Fprintf(w,
+			`{"results": [{"extensions": [{"extensionName": "ext%d-1"}, {"extensionName": "ext%d-2"}]}], "pagingToken": %q}`,
+			page, page, next,
+		)
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return New(u.Scheme, u.Host)
+}
+
+func TestQueryPaging(t *testing.T) {
+	z := zest.New(t)
+
+	tests := []struct {
+		limit      int
+		wantNames  int
+		wantTokens []string
+	}{
+		// Unbounded queries yield the first page only
+		{0, 2, []string{""}},
+		{3, 3, []string{"", "page-2"}},
+		{10, 6, []string{"", "page-2", "page-3"}},
+	}
+	for _, test := range tests {
+		var tokens []string
+		g := testPagingGallery(t, &tokens)
+
+		var names []string
+		for ext, err := range g.Query(context.Background(), QueryOptions{Term: "ext", PageSize: 2, Limit: test.limit}) {
+			z.Assert(err == nil, "limit %d: unexpected error: %s", test.limit, err)
+			names = append(names, ext.Name)
+		}
+		z.Assert(len(names) == test.wantNames, "limit %d: expected %d results, got %q", test.limit, test.wantNames, names)
+		z.Assert(fmt.Sprint(tokens) == fmt.Sprint(test.wantTokens), "limit %d: expected paging tokens %q, got %q", test.limit, test.wantTokens, tokens)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/echo"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+// ExtensionDetails is the gallery metadata of a single extension
+type ExtensionDetails struct {
+	Extension         string    `json:"extension"`
+	DisplayName       string    `json:"display_name"`
+	Description       string    `json:"description"`
+	Publisher         string    `json:"publisher"`
+	PublisherVerified bool      `json:"publisher_verified"`
+	Version           string    `json:"version"`
+	Preview           bool      `json:"preview"`
+	Published         time.Time `json:"published"`
+	LastUpdated       time.Time `json:"last_updated"`
+	Installs          float64   `json:"installs"`
+	Rating            float64   `json:"rating"`
+	RatingCount       float64   `json:"rating_count"`
+}
+
+func (ExtensionDetails) Columns() []string {
+	return []string{
+		"Extension",
+		"Name",
+		"Description",
+		"Publisher",
+		"Verified",
+		"Version",
+		"Preview",
+		"Published",
+		"Updated",
+		"Installs",
+		"Rating",
+		"Ratings",
+	}
+}
+
+func (self ExtensionDetails) Row() []string {
+	return []string{
+		self.Extension,
+		self.DisplayName,
+		self.Description,
+		self.Publisher,
+		strconv.FormatBool(self.PublisherVerified),
+		self.Version,
+		strconv.FormatBool(self.Preview),
+		self.Published.Format(time.DateOnly),
+		self.LastUpdated.Format(time.DateOnly),
+		strconv.FormatFloat(self.Installs, 'f', 0, 64),
+		strconv.FormatFloat(self.Rating, 'f', 2, 64),
+		strconv.FormatFloat(self.RatingCount, 'f', 0, 64),
+	}
+}
+
+func ExtensionInfo(ctx context.Context, g *Galleries, cfg *Config, format Format, cmd argv.Command) error {
+	if len(cmd.Args) == 0 {
+		return UsageError("No extensions received.")
+	}
+	resolve, err := ParseResolveOptions(cfg, cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+
+	var details []ExtensionDetails
+	var errs []error
+	for _, input := range cmd.Args {
+		pub, id, _, err := ParseExtension(input)
+		if err != nil {
+			errs = append(errs, fmt.Errorf(
+				"failed to parse extension input[%s]: %w",
+				input, err,
+			))
+			continue
+		}
+
+		meta, err := g.GetExtensionMeta(ctx, pub, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to look up [%s]: %w", input, err))
+			continue
+		}
+
+		// The version shown is that `install` would select
+		resolved, err := ResolveExtension(ctx, g, pub, id, "", resolve)
+		if errors.Is(err, ErrNoVersion) {
+			echo.Debugf("No installable version of [%s]: %s.", input, err)
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		details = append(details, extensionDetails(meta, resolved.Version))
+		recentExtensions.Add(details[len(details)-1].Extension)
+	}
+
+	// Tables of a dozen columns are unreadable, so in table form each extension
+	// is written as a listing of labelled values instead
+	if format == FormatTable {
+		for i, d := range details {
+			if i > 0 {
+				fmt.Println()
+			}
+			printDetails(os.Stdout, d.Columns(), d.Row())
+		}
+		return errors.Join(errs...)
+	}
+
+	return errors.Join(Render(os.Stdout, format, details), errors.Join(errs...))
+}
+
+// extensionDetails produces the ExtensionDetails of `meta`, its latest version
+// being `version`
+func extensionDetails(meta gallery.ExtensionMeta, version string) ExtensionDetails {
+	return ExtensionDetails{
+		Extension:         meta.Publisher.Name + "." + meta.Name,
+		DisplayName:       meta.DisplayName,
+		Description:       meta.Description,
+		Publisher:         meta.Publisher.DisplayName,
+		PublisherVerified: meta.Publisher.Flags.Verified || meta.Publisher.DomainVerified,
+		Version:           version,
+		Preview:           meta.Flags.Preview,
+		Published:         meta.Published,
+		LastUpdated:       meta.LastUpdated,
+		Installs:          meta.Statistics.Installs(),
+		Rating:            meta.Statistics.AverageRating(),
+		RatingCount:       meta.Statistics.RatingCount(),
+	}
+}
+
+// printDetails writes each of `labels` alongside its corresponding value in
+// `values`, one per line
+func printDetails(w io.Writer, labels, values []string) {
+	width := 0
+	for _, label := range labels {
+		width = max(width, len(label))
+	}
+	for i, label := range labels {
+		fmt.Fprintf(w, "%-*s%s%s\n", width, label, colPadding, values[i])
+	}
+}
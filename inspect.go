@@ -0,0 +1,222 @@
+package main
+
+import (
+	"archive/zip"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/illbjorn/argv"
+)
+
+const (
+	// sizeGroupPackage groups the files outside the extension's directory (ex:
+	// the package manifest)
+	sizeGroupPackage = "(package)"
+
+	// sizeGroupRoot groups the files at the root of the extension's directory
+	sizeGroupRoot = "(root)"
+)
+
+// VSIXReport is the offline analysis of a VSIX package
+type VSIXReport struct {
+	Path             string            `json:"path"`
+	Publisher        string            `json:"publisher"`
+	Name             string            `json:"name"`
+	Version          string            `json:"version"`
+	TargetPlatform   string            `json:"target_platform"`
+	DisplayName      string            `json:"display_name"`
+	Engines          map[string]string `json:"engines"`
+	ActivationEvents []string          `json:"activation_events"`
+	Commands         []string          `json:"commands"`
+	Languages        []string          `json:"languages"`
+	Debuggers        []string          `json:"debuggers"`
+	Dependencies     []string          `json:"dependencies"`
+	ExtensionPack    []string          `json:"extension_pack"`
+	NativeBinaries   []VSIXFile        `json:"native_binaries"`
+	ContentTypes     map[string]string `json:"content_types"`
+	Files            int               `json:"files"`
+	Size             int64             `json:"size"`
+	CompressedSize   int64             `json:"compressed_size"`
+	Breakdown        []SizeGroup       `json:"breakdown"`
+}
+
+// SizeGroup totals the files of a package beneath a single directory
+type SizeGroup struct {
+	Group string `json:"group"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+func (VSIXReport) Columns() []string {
+	return []string{
+		"Path",
+		"Extension",
+		"Version",
+		"Platform",
+		"Name",
+		"Engines",
+		"Activation",
+		"Commands",
+		"Languages",
+		"Debuggers",
+		"Dependencies",
+		"Pack",
+		"Native",
+		"Files",
+		"Size",
+		"Breakdown",
+	}
+}
+
+func (self VSIXReport) Row() []string {
+	var engines []string
+	for _, engine := range slices.Sorted(maps.Keys(self.Engines)) {
+		engines = append(engines, engine+" "+self.Engines[engine])
+	}
+	var native []string
+	for _, f := range self.NativeBinaries {
+		native = append(native, f.Name+" ("+f.Native+")")
+	}
+	var breakdown []string
+	for _, group := range self.Breakdown {
+		breakdown = append(breakdown, fmt.Sprintf("%s %s (%d files)", group.Group, formatBytes(group.Size), group.Files))
+	}
+
+	return []string{
+		self.Path,
+		self.Publisher + "." + self.Name,
+		self.Version,
+		self.TargetPlatform,
+		self.DisplayName,
+		strings.Join(engines, ", "),
+		strings.Join(self.ActivationEvents, ", "),
+		strings.Join(self.Commands, ", "),
+		strings.Join(self.Languages, ", "),
+		strings.Join(self.Debuggers, ", "),
+		strings.Join(self.Dependencies, ", "),
+		strings.Join(self.ExtensionPack, ", "),
+		strings.Join(native, ", "),
+		strconv.Itoa(self.Files),
+		fmt.Sprintf("%s (%s compressed)", formatBytes(self.Size), formatBytes(self.CompressedSize)),
+		strings.Join(breakdown, ", "),
+	}
+}
+
+// InspectCommand reports on each of the VSIX packages in `cmd`
+func InspectCommand(format Format, cmd argv.Command) error {
+	if len(cmd.Args) == 0 {
+		return UsageError("No VSIX packages received.")
+	}
+
+	var reports []VSIXReport
+	var errs []error
+	for _, path := range cmd.Args {
+		report, err := InspectVSIX(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	// As with `info`, reports are written as listings of labelled values
+	if format == FormatTable {
+		for i, report := range reports {
+			if i > 0 {
+				fmt.Println()
+			}
+			printDetails(os.Stdout, report.Columns(), report.Row())
+		}
+		return errors.Join(errs...)
+	}
+
+	return errors.Join(Render(os.Stdout, format, reports), errors.Join(errs...))
+}
+
+// InspectVSIX analyzes the VSIX package at `path`
+func InspectVSIX(path string) (VSIXReport, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return VSIXReport{}, fmt.Errorf("failed to open [%s]: %w", path, err)
+	}
+	defer zr.Close()
+
+	pkg, err := ReadVSIX(&zr.Reader)
+	if err != nil {
+		return VSIXReport{}, fmt.Errorf("[%s]: %w", path, err)
+	}
+
+	report := inspectPackage(pkg)
+	report.Path = path
+	return report, nil
+}
+
+// inspectPackage produces the report of `pkg`
+func inspectPackage(pkg *VSIXPackage) VSIXReport {
+	manifest := pkg.Manifest
+	self := VSIXReport{
+		Publisher:        cmp.Or(pkg.Identity.Publisher, manifest.Publisher),
+		Name:             cmp.Or(pkg.Identity.Name, manifest.Name),
+		Version:          pkg.Identity.Version,
+		TargetPlatform:   cmp.Or(pkg.Identity.TargetPlatform, platformUniversal),
+		DisplayName:      manifest.DisplayName,
+		Engines:          manifest.Engines,
+		ActivationEvents: manifest.ActivationEvents,
+		Commands:         manifest.Contributions(contribCommands),
+		Languages:        manifest.Contributions(contribLanguages),
+		Debuggers:        manifest.Contributions(contribDebuggers),
+		Dependencies:     manifest.ExtensionDependencies,
+		ExtensionPack:    manifest.ExtensionPack,
+		ContentTypes:     pkg.ContentTypes,
+		Files:            len(pkg.Files),
+	}
+
+	groups := make(map[string]*SizeGroup)
+	for _, f := range pkg.Files {
+		self.Size += f.Size
+		self.CompressedSize += f.CompressedSize
+		if f.Native != "" {
+			self.NativeBinaries = append(self.NativeBinaries, f)
+		}
+
+		name := sizeGroup(f.Name)
+		group, ok := groups[name]
+		if !ok {
+			group = &SizeGroup{Group: name}
+			groups[name] = group
+		}
+		group.Files++
+		group.Size += f.Size
+	}
+
+	// Largest first
+	for _, group := range groups {
+		self.Breakdown = append(self.Breakdown, *group)
+	}
+	slices.SortFunc(self.Breakdown, func(a, b SizeGroup) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), strings.Compare(a.Group, b.Group))
+	})
+
+	return self
+}
+
+// sizeGroup produces the size breakdown group of package file `name`: the
+// top-level directory within `extension/` holding it
+func sizeGroup(name string) string {
+	rest, ok := strings.CutPrefix(name, "extension/")
+	if !ok {
+		return sizeGroupPackage
+	}
+	dir, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return sizeGroupRoot
+	}
+	return path.Clean(dir)
+}
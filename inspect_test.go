@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+const testPackageJSON = `{
+  "name": "tools",
+  "publisher": "ourcorp",
+  "version": "1.0.0",
+  "engines": {"vscode": "^1.90.0"},
+  "activationEvents": ["onLanguage:go"],
+  "extensionDependencies": ["golang.go"],
+  "contributes": {
+    "commands": [{"command": "tools.run", "title": "Run"}, {"command": "tools.stop", "title": "Stop"}],
+    "languages": [{"id": "tool"}],
+    "debuggers": {"type": "tooldbg"}
+  }
+}`
+
+func TestInspectVSIX(t *testing.T) {
+	z := zest.New(t)
+
+	pe := make([]byte, 0x100)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x80
+	copy(pe[0x80:], "PE\x00\x00")
+
+	path := filepath.Join(t.TempDir(), "tools.vsix")
+	err := os.WriteFile(path, testZip(t, map[string]string{
+		vsixManifestName:              testVSIXManifest,
+		vsixContentTypesName:          `<Types><Default Extension=".json" ContentType="application/json"/></Types>`,
+		vsixPackageJSONName:           testPackageJSON,
+		"extension/bin/tool":          "\x7fELF\x02\x01\x01" + strings.Repeat("\x00", 64),
+		"extension/bin/tool.exe":      string(pe),
+		"extension/bin/tool.dylib":    "\xcf\xfa\xed\xfe" + strings.Repeat("\x00", 64),
+		"extension/lib/Tool.class":    "\xca\xfe\xba\xbe\x00\x00\x00\x34",
+		"extension/dist/extension.js": strings.Repeat("x", 1000),
+	}), fileModeRW)
+	z.Assert(err == nil, "failed to write package: %s", err)
+
+	report, err := InspectVSIX(path)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(report.Publisher == "ourcorp" && report.Name == "tools", "unexpected identity [%s.%s]", report.Publisher, report.Name)
+	z.Assert(report.TargetPlatform == "linux-x64", "unexpected platform [%s]", report.TargetPlatform)
+	z.Assert(report.Engines["vscode"] == "^1.90.0", "unexpected engines [%v]", report.Engines)
+	z.Assert(slices.Equal(report.Commands, []string{"tools.run", "tools.stop"}), "unexpected commands [%v]", report.Commands)
+	z.Assert(slices.Equal(report.Languages, []string{"tool"}), "unexpected languages [%v]", report.Languages)
+	z.Assert(slices.Equal(report.Debuggers, []string{"tooldbg"}), "unexpected debuggers [%v]", report.Debuggers)
+	z.Assert(slices.Equal(report.Dependencies, []string{"golang.go"}), "unexpected dependencies [%v]", report.Dependencies)
+	z.Assert(report.ContentTypes["json"] == "application/json", "unexpected content types [%v]", report.ContentTypes)
+	z.Assert(report.Files == 8, "expected 8 files, got %d", report.Files)
+
+	native := make(map[string]string)
+	for _, f := range report.NativeBinaries {
+		native[f.Name] = f.Native
+	}
+	z.Assert(len(native) == 3, "expected 3 native binaries, got [%v]", native)
+	z.Assert(native["extension/bin/tool"] == nativeELF, "expected ELF, got [%s]", native["extension/bin/tool"])
+	z.Assert(native["extension/bin/tool.exe"] == nativePE, "expected PE, got [%s]", native["extension/bin/tool.exe"])
+	z.Assert(native["extension/bin/tool.dylib"] == nativeMachO, "expected Mach-O, got [%s]", native["extension/bin/tool.dylib"])
+
+	z.Assert(report.Breakdown[0].Group == "dist", "expected dist largest, got [%s]", report.Breakdown[0].Group)
+}
+
+func TestReadNative(t *testing.T) {
+	z := zest.New(t)
+
+	// testPE produces a DOS header whose PE header offset (`e_lfanew`) is
+	// `offset`, followed by a PE signature at `sig`
+	testPE := func(offset, sig uint32) string {
+		pe := make([]byte, max(0x100, sig+4))
+		copy(pe, "MZ")
+		binary.LittleEndian.PutUint32(pe[0x3c:], offset)
+		copy(pe[sig:], "PE\x00\x00")
+		return string(pe)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"pe", testPE(0x80, 0x80), nativePE},
+		{"pe signature past the sniffed bytes", testPE(0x400, 0x400), nativePE},
+		{"pe signature straddling the sniffed bytes", testPE(nativeSniffLen-2, nativeSniffLen-2), nativePE},
+		{"dos stub without pe signature", testPE(0x40, 0x80), ""},
+		{"dos stub without pe signature past the sniffed bytes", testPE(0x300, 0x400), ""},
+		{"pe offset overflowing int32", testPE(0xFFFFFFF0, 0x80), nativePE},
+		{"pe offset past the end", testPE(0xFE, 0x80), nativePE},
+		{"pe offset past the sniffed bytes and the end", testPE(nativeSniffLen, 0x80), nativePE},
+		{"elf", "\x7fELF\x02\x01\x01", nativeELF},
+		{"short", "MZ", ""},
+		{"text", "#!/bin/sh\necho hi\n", ""},
+	} {
+		got, err := readNative(strings.NewReader(tc.input))
+		z.Assert(err == nil, "[%s]: unexpected error: %s", tc.name, err)
+		z.Assert(got == tc.want, "[%s]: expected [%s], got [%s]", tc.name, tc.want, got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/echo"
+)
+
+// InstalledExtension describes a single extension found in an extension
+// directory
+type InstalledExtension struct {
+	Publisher      string `json:"publisher"`
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+	TargetPlatform string `json:"target_platform,omitempty"`
+	DisplayName    string `json:"display_name"`
+	Path           string `json:"path"`
+}
+
+// ID produces the `publisher.name` identifier of the extension
+func (self InstalledExtension) ID() string {
+	return self.Publisher + "." + self.Name
+}
+
+func (InstalledExtension) Columns() []string {
+	return []string{"Name", "Extension", "Version", "Path"}
+}
+
+func (self InstalledExtension) Row() []string {
+	return []string{self.DisplayName, self.ID(), self.Version, self.Path}
+}
+
+// InstalledExtensions produces every extension installed in `extDir`, sorted by
+// identifier
+//
+// Each subdirectory of `extDir` containing a readable `package.json` is
+// considered an installed extension.
+func InstalledExtensions(extDir string) ([]InstalledExtension, error) {
+	entries, err := os.ReadDir(extDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extension directory [%s]: %w", extDir, err)
+	}
+
+	var installed []InstalledExtension
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(extDir, entry.Name())
+		manifest, err := readPackageManifest(filepath.Join(path, "package.json"))
+		if errors.Is(err, os.ErrNotExist) {
+			echo.Debugf("Skipping [%s] (no package.json).", path)
+			continue
+		} else if err != nil {
+			echo.Debugf("Skipping [%s]: %s.", path, err)
+			continue
+		}
+
+		installed = append(installed, InstalledExtension{
+			Publisher:      manifest.Publisher,
+			Name:           manifest.Name,
+			Version:        manifest.Version,
+			TargetPlatform: manifest.Metadata.TargetPlatform,
+			DisplayName:    manifest.DisplayName,
+			Path:           path,
+		})
+	}
+
+	slices.SortFunc(installed, func(a, b InstalledExtension) int {
+		return strings.Compare(strings.ToLower(a.ID()), strings.ToLower(b.ID()))
+	})
+
+	return installed, nil
+}
+
+func readPackageManifest(path string) (*ExtensionManifest, error) {
+	f, err := os.OpenFile(path, fileFlagsRead, fileModeRW)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	manifest := new(ExtensionManifest)
+	if err := json.NewDecoder(f).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode [%s]: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// readInstalledPackage reads the extension installed at `ext.Path` as though it
+// were a VSIX package, its files named as they would be in one (ex:
+// `extension/package.json`)
+func readInstalledPackage(ext InstalledExtension) (*VSIXPackage, error) {
+	manifest, err := readPackageManifest(filepath.Join(ext.Path, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &VSIXPackage{
+		Identity: VSIXIdentity{
+			Publisher:      ext.Publisher,
+			Name:           ext.Name,
+			Version:        ext.Version,
+			TargetPlatform: ext.TargetPlatform,
+		},
+		Manifest:  *manifest,
+		extension: os.DirFS(ext.Path),
+	}
+
+	err = filepath.WalkDir(ext.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ext.Path, path)
+		if err != nil {
+			return err
+		}
+
+		file := VSIXFile{Name: "extension/" + filepath.ToSlash(rel), Size: info.Size()}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		file.Native, err = readNative(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read [%s]: %w", path, err)
+		}
+
+		pkg.Files = append(pkg.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read [%s]: %w", ext.Path, err)
+	}
+
+	if pkg.LicenseFile = pkg.licenseFile(); pkg.LicenseFile != "" {
+		path := filepath.Join(ext.Path, filepath.FromSlash(strings.TrimPrefix(pkg.LicenseFile, "extension/")))
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(io.LimitReader(f, maxLicenseSize))
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read [%s]: %w", path, err)
+		}
+		pkg.License = string(b)
+	}
+
+	return pkg, nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/argv"
+)
+
+// License categories, from least to most restrictive
+const (
+	licensePublicDomain = "public-domain"
+	licensePermissive   = "permissive"
+	licenseWeakCopyleft = "weak-copyleft"
+	licenseCopyleft     = "copyleft"
+	licenseProprietary  = "proprietary"
+	licenseUnknown      = "unknown"
+)
+
+// License sources
+const (
+	licenseSourceManifest = "package.json"
+	licenseSourceFile     = "license_file"
+)
+
+// licenseUnlicensed is the npm license field of packages not licensed for use
+// by others
+const licenseUnlicensed = "UNLICENSED"
+
+// spdxCategories are the SPDX identifiers of common licenses, by category
+var spdxCategories = map[string][]string{
+	licensePublicDomain: {"Unlicense", "CC0-1.0", "0BSD", "WTFPL"},
+	licensePermissive: {
+		"MIT", "MIT-0", "ISC", "BSD-2-Clause", "BSD-3-Clause", "Apache-2.0",
+		"Zlib", "BSL-1.0", "Python-2.0", "Artistic-2.0", "BlueOak-1.0.0",
+		"CC-BY-3.0", "CC-BY-4.0", "X11", "PostgreSQL", "NCSA", "Unicode-DFS-2016",
+	},
+	licenseWeakCopyleft: {
+		"LGPL-2.0-only", "LGPL-2.0-or-later", "LGPL-2.1-only", "LGPL-2.1-or-later",
+		"LGPL-3.0-only", "LGPL-3.0-or-later", "LGPL-2.0", "LGPL-2.1", "LGPL-3.0",
+		"MPL-1.1", "MPL-2.0", "EPL-1.0", "EPL-2.0", "CDDL-1.0", "CDDL-1.1",
+		"CC-BY-SA-4.0",
+	},
+	licenseCopyleft: {
+		"GPL-2.0-only", "GPL-2.0-or-later", "GPL-3.0-only", "GPL-3.0-or-later",
+		"AGPL-3.0-only", "AGPL-3.0-or-later", "GPL-2.0", "GPL-3.0", "AGPL-3.0",
+		"EUPL-1.2", "OSL-3.0",
+	},
+}
+
+// spdxLicense is a known SPDX license
+type spdxLicense struct {
+	id       string
+	category string
+}
+
+// spdxLicenses maps the lowercased identifiers of spdxCategories to their
+// canonical form and category
+var spdxLicenses = func() map[string]spdxLicense {
+	licenses := make(map[string]spdxLicense)
+	for category, ids := range spdxCategories {
+		for _, id := range ids {
+			licenses[strings.ToLower(id)] = spdxLicense{id, category}
+		}
+	}
+	return licenses
+}()
+
+// licenseTexts identify licenses by phrases of their text (lowercased, with
+// whitespace collapsed), checked in order as some texts embed others' phrases
+//
+// Texts alone can't tell `-only` from `-or-later` GNU licenses, which are
+// identified by their (deprecated) version-only identifiers.
+var licenseTexts = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license version 2.1"}},
+	{"LGPL-2.0", []string{"gnu library general public license version 2"}},
+	{"GPL-3.0", []string{"gnu general public license version 3"}},
+	{"GPL-2.0", []string{"gnu general public license version 2"}},
+	{"MPL-2.0", []string{"mozilla public license version 2.0"}},
+	{"EPL-2.0", []string{"eclipse public license - v 2.0"}},
+	{"EPL-1.0", []string{"eclipse public license - v 1.0"}},
+	{"Apache-2.0", []string{"apache license version 2.0"}},
+	{"BSL-1.0", []string{"boost software license - version 1.0"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose", "provided that the above copyright notice"}},
+	{"0BSD", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Zlib", []string{"altered source versions must be plainly marked"}},
+}
+
+// proprietaryPhrases mark license texts as proprietary, where no open source
+// license is recognized
+var proprietaryPhrases = []string{
+	"all rights reserved",
+	"software license terms",
+	"proprietary",
+	"end user license agreement",
+}
+
+// LicenseReport is the license of a single extension
+type LicenseReport struct {
+	Extension string `json:"extension"`
+	Version   string `json:"version"`
+
+	// Declared is the license field of the extension's `package.json`, as
+	// written
+	Declared string `json:"declared,omitempty"`
+
+	// License is the SPDX license expression identified, or the empty string
+	// if none was
+	License  string `json:"license"`
+	Category string `json:"category"`
+
+	// Source is where the license was identified: `package.json` or
+	// `license_file`
+	Source string `json:"source,omitempty"`
+
+	// File is the extension's license file (ex: `LICENSE.md`), if any
+	File string `json:"file,omitempty"`
+
+	// Flagged is set for proprietary and unidentified licenses
+	Flagged bool `json:"flagged"`
+}
+
+func (LicenseReport) Columns() []string {
+	return []string{"Extension", "Version", "License", "Category", "Source", "File", "Flagged"}
+}
+
+func (self LicenseReport) Row() []string {
+	flagged := ""
+	if self.Flagged {
+		flagged = "yes"
+	}
+	return []string{
+		self.Extension,
+		self.Version,
+		cmp.Or(self.License, self.Declared),
+		self.Category,
+		self.Source,
+		self.File,
+		flagged,
+	}
+}
+
+// LicensesCommand reports the license of each extension (or `.vsix` file) in
+// `cmd`, of every installed extension with `--installed` and of each of the
+// project's declared extensions with `--lockfile`
+func LicensesCommand(ctx context.Context, g *Galleries, cfg *Config, format Format, cmd argv.Command) error {
+	inputs, installed, err := packageInputs(cfg, cmd)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 && !installed {
+		return UsageError("No extensions received (or --%s, --%s).", flagInstalled, flagLockfile)
+	}
+
+	pkgs, errs := loadPackages(ctx, g, cfg, cmd, inputs, installed)
+	reports := make([]LicenseReport, len(pkgs))
+	for i, pkg := range pkgs {
+		reports[i] = PackageLicense(pkg)
+	}
+
+	return errors.Join(Render(os.Stdout, format, reports), errors.Join(errs...))
+}
+
+// PackageLicense identifies the license of `pkg`, from its license field where
+// a known SPDX expression and otherwise from its license file
+func PackageLicense(pkg *VSIXPackage) LicenseReport {
+	manifest := pkg.Manifest
+	self := LicenseReport{
+		Extension: cmp.Or(pkg.Identity.Publisher, manifest.Publisher) + "." + cmp.Or(pkg.Identity.Name, manifest.Name),
+		Version:   cmp.Or(pkg.Identity.Version, manifest.Version),
+		Declared:  strings.TrimSpace(manifest.License),
+		Category:  licenseUnknown,
+	}
+	if pkg.LicenseFile != "" {
+		self.File = strings.TrimPrefix(pkg.LicenseFile, "extension/")
+	}
+	_, seeFile := seeLicenseIn(self.Declared)
+
+	switch {
+	case strings.EqualFold(self.Declared, licenseUnlicensed):
+		self.Category, self.Source = licenseProprietary, licenseSourceManifest
+
+	case self.Declared != "" && !seeFile:
+		if expr, category := parseLicenseExpression(self.Declared); category != licenseUnknown {
+			self.License, self.Category, self.Source = expr, category, licenseSourceManifest
+			break
+		}
+		fallthrough
+
+	default:
+		if pkg.License == "" {
+			break
+		}
+		id, category := detectLicense(pkg.License)
+		if category != licenseUnknown {
+			self.License, self.Category, self.Source = id, category, licenseSourceFile
+		}
+	}
+
+	self.Flagged = self.Category == licenseProprietary || self.Category == licenseUnknown
+	return self
+}
+
+// parseLicenseExpression canonicalizes the identifiers of SPDX license
+// expression `expr` (ex: `(mit OR apache-2.0)`), producing its category
+//
+// Choices (`OR`) take the category of the least restrictive license and
+// combinations (`AND`) that of the most restrictive. Expressions using any
+// unknown license are unknown unless a choice of a known one.
+func parseLicenseExpression(expr string) (string, string) {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	tokens := strings.Fields(expr)
+
+	var ids []string
+	var categories []string
+	anyUnknown, choice, exception := false, false, false
+	for i, token := range tokens {
+		switch upper := strings.ToUpper(token); {
+		case upper == "OR":
+			choice = true
+			tokens[i] = upper
+		case upper == "AND" || upper == "WITH":
+			exception = upper == "WITH"
+			tokens[i] = upper
+		case token == "(" || token == ")":
+		case exception:
+			// License exceptions (ex: `Classpath-exception-2.0`) qualify the
+			// license before them
+			exception = false
+		default:
+			id, orLater := strings.CutSuffix(token, "+")
+			license, ok := spdxLicenses[strings.ToLower(id)]
+			if !ok {
+				anyUnknown = true
+				continue
+			}
+			tokens[i] = license.id
+			if orLater {
+				tokens[i] += "+"
+			}
+			ids = append(ids, license.id)
+			categories = append(categories, license.category)
+		}
+	}
+	if len(ids) == 0 || (anyUnknown && !choice) {
+		return "", licenseUnknown
+	}
+
+	ranked := func(a, b string) int {
+		return cmp.Compare(licenseRank(a), licenseRank(b))
+	}
+	canonical := strings.NewReplacer("( ", "(", " )", ")").Replace(strings.Join(tokens, " "))
+	if choice {
+		return canonical, slices.MinFunc(categories, ranked)
+	}
+	return canonical, slices.MaxFunc(categories, ranked)
+}
+
+// licenseRank orders license categories from least to most restrictive
+func licenseRank(category string) int {
+	return slices.Index([]string{
+		licensePublicDomain,
+		licensePermissive,
+		licenseWeakCopyleft,
+		licenseCopyleft,
+		licenseProprietary,
+		licenseUnknown,
+	}, category)
+}
+
+// detectLicense identifies the license of license file `text`, producing its
+// SPDX identifier (empty if none) and category
+func detectLicense(text string) (string, string) {
+	text = strings.Join(strings.Fields(strings.ToLower(text)), " ")
+
+	for _, license := range licenseTexts {
+		if !containsAll(text, license.phrases) {
+			continue
+		}
+		return license.id, spdxLicenses[strings.ToLower(license.id)].category
+	}
+
+	for _, phrase := range proprietaryPhrases {
+		if strings.Contains(text, phrase) {
+			return "", licenseProprietary
+		}
+	}
+	return "", licenseUnknown
+}
+
+// containsAll reports whether `s` contains every one of `substrs`
+func containsAll(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if !strings.Contains(s, substr) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLicenseFile reports whether `name` is conventionally a license file (ex:
+// `LICENSE.md`, `COPYING`)
+func isLicenseFile(name string) bool {
+	base := strings.ToUpper(strings.TrimSuffix(name, path.Ext(name)))
+	return base == "LICENSE" || base == "LICENCE" || base == "COPYING"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+func TestPackageLicense(t *testing.T) {
+	z := zest.New(t)
+
+	const mitText = `MIT License
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software")...`
+
+	tests := []struct {
+		name     string
+		license  string
+		files    map[string]string
+		expr     string
+		category string
+		source   string
+		flagged  bool
+	}{
+		{"spdx", `"mit"`, nil, "MIT", licensePermissive, licenseSourceManifest, false},
+		{"choice", `"(MIT OR GPL-3.0-only)"`, nil, "(MIT OR GPL-3.0-only)", licensePermissive, licenseSourceManifest, false},
+		{"combination", `"Apache-2.0 AND LGPL-2.1-or-later"`, nil, "Apache-2.0 AND LGPL-2.1-or-later", licenseWeakCopyleft, licenseSourceManifest, false},
+		{"exception", `"GPL-2.0-only WITH Classpath-exception-2.0"`, nil, "GPL-2.0-only WITH Classpath-exception-2.0", licenseCopyleft, licenseSourceManifest, false},
+		{"unlicensed", `"UNLICENSED"`, nil, "", licenseProprietary, licenseSourceManifest, true},
+		{
+			"see license in", `"SEE LICENSE IN docs/TERMS.txt"`,
+			map[string]string{"extension/docs/TERMS.txt": mitText},
+			"MIT", licensePermissive, licenseSourceFile, false,
+		},
+		{
+			"license file", `""`,
+			map[string]string{"extension/LICENSE.md": "Copyright (c) Contoso. All rights reserved."},
+			"", licenseProprietary, licenseSourceFile, true,
+		},
+		{"unknown", `"Contoso-Custom"`, nil, "", licenseUnknown, "", true},
+	}
+
+	for _, test := range tests {
+		files := test.files
+		if files == nil {
+			files = map[string]string{}
+		}
+		got := PackageLicense(testPackage(t, `{"license": `+test.license+`}`, files))
+		z.Assert(got.License == test.expr, "%s: expected license [%s], got [%s]", test.name, test.expr, got.License)
+		z.Assert(got.Category == test.category, "%s: expected category [%s], got [%s]", test.name, test.category, got.Category)
+		z.Assert(got.Source == test.source, "%s: expected source [%s], got [%s]", test.name, test.source, got.Source)
+		z.Assert(got.Flagged == test.flagged, "%s: expected flagged [%t], got [%t]", test.name, test.flagged, got.Flagged)
+	}
+}
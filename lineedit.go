@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrInterrupt = fmt.Errorf("interrupted")
+)
+
+// Completer produces the completion candidates for the word ending at `pos` in
+// `line`, along with the offset in `line` at which that word begins
+type Completer func(line []rune, pos int) (candidates []string, start int)
+
+// LineEditor reads lines of input with basic editing support
+//
+// When attached to a terminal, the editor supports cursor movement, history
+// navigation and tab completion using the usual readline bindings. Otherwise,
+// lines are read as-is.
+type LineEditor struct {
+	Prompt   string
+	History  []string
+	Complete Completer
+
+	in  *os.File
+	out io.Writer
+	r   *bufio.Reader
+
+	// Line editor state
+	line    []rune
+	pos     int
+	histIdx int
+	draft   []rune
+}
+
+func NewLineEditor(in *os.File, out io.Writer) *LineEditor {
+	return &LineEditor{
+		in:  in,
+		out: out,
+		r:   bufio.NewReader(in),
+	}
+}
+
+// ReadLine reads a single line of input
+//
+// `io.EOF` is returned on end of input (or Ctrl-D on an empty line) and
+// `ErrInterrupt` if the line was abandoned with Ctrl-C.
+func (self *LineEditor) ReadLine() (string, error) {
+	// If we're not attached to a terminal (or it can't be put into raw mode),
+	// just read a line
+	if !isTerminal(self.in) {
+		return self.readPlain(false)
+	}
+	restore, err := makeRaw(self.in)
+	if err != nil {
+		return self.readPlain(true)
+	}
+	defer restore()
+
+	return self.readEdited()
+}
+
+func (self *LineEditor) readPlain(prompt bool) (string, error) {
+	if prompt {
+		fmt.Fprint(self.out, self.Prompt)
+	}
+	line, err := self.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+func (self *LineEditor) readEdited() (string, error) {
+	self.line = self.line[:0]
+	self.pos = 0
+	self.histIdx = len(self.History)
+	self.draft = nil
+	self.refresh()
+
+	for {
+		r, _, err := self.r.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyCR, keyLF:
+			fmt.Fprint(self.out, "\r\n")
+			return string(self.line), nil
+
+		case keyCtrlC:
+			fmt.Fprint(self.out, "^C\r\n")
+			return "", ErrInterrupt
+
+		case keyCtrlD:
+			if len(self.line) == 0 {
+				fmt.Fprint(self.out, "\r\n")
+				return "", io.EOF
+			}
+			self.deleteAt(self.pos)
+
+		case keyBackspace, keyDelete:
+			if self.pos > 0 {
+				self.pos--
+				self.deleteAt(self.pos)
+			}
+
+		case keyCtrlA:
+			self.pos = 0
+		case keyCtrlE:
+			self.pos = len(self.line)
+		case keyCtrlB:
+			self.pos = max(self.pos-1, 0)
+		case keyCtrlF:
+			self.pos = min(self.pos+1, len(self.line))
+		case keyCtrlP:
+			self.historyPrev()
+		case keyCtrlN:
+			self.historyNext()
+
+		case keyCtrlK:
+			self.line = self.line[:self.pos]
+
+		case keyCtrlU:
+			self.line = append(self.line[:0], self.line[self.pos:]...)
+			self.pos = 0
+
+		case keyCtrlW:
+			// Delete the word preceding the cursor (and any trailing spaces)
+			start := self.pos
+			for start > 0 && self.line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && self.line[start-1] != ' ' {
+				start--
+			}
+			self.line = append(self.line[:start], self.line[self.pos:]...)
+			self.pos = start
+
+		case keyCtrlL:
+			fmt.Fprint(self.out, "\x1b[H\x1b[2J")
+
+		case keyTab:
+			self.complete()
+
+		case keyEscape:
+			self.escape()
+
+		default:
+			if r < ' ' {
+				continue
+			}
+			self.line = append(self.line[:self.pos], append([]rune{r}, self.line[self.pos:]...)...)
+			self.pos++
+		}
+
+		self.refresh()
+	}
+}
+
+// escape handles the ANSI escape sequences produced by the arrow, home, end and
+// delete keys
+func (self *LineEditor) escape() {
+	b, err := self.r.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return
+	}
+	b, err = self.r.ReadByte()
+	if err != nil {
+		return
+	}
+
+	// Sequences like `ESC [ 3 ~` carry a numeric parameter
+	if b >= '0' && b <= '9' {
+		param := b
+		for b != '~' {
+			if b, err = self.r.ReadByte(); err != nil {
+				return
+			}
+		}
+		switch param {
+		case '1', '7':
+			self.pos = 0
+		case '4', '8':
+			self.pos = len(self.line)
+		case '3':
+			self.deleteAt(self.pos)
+		}
+		return
+	}
+
+	switch b {
+	case 'A':
+		self.historyPrev()
+	case 'B':
+		self.historyNext()
+	case 'C':
+		self.pos = min(self.pos+1, len(self.line))
+	case 'D':
+		self.pos = max(self.pos-1, 0)
+	case 'H':
+		self.pos = 0
+	case 'F':
+		self.pos = len(self.line)
+	}
+}
+
+func (self *LineEditor) deleteAt(i int) {
+	if i < len(self.line) {
+		self.line = append(self.line[:i], self.line[i+1:]...)
+	}
+}
+
+func (self *LineEditor) historyPrev() {
+	if self.histIdx == 0 {
+		return
+	}
+	// Stash whatever was typed before we started browsing
+	if self.histIdx == len(self.History) {
+		self.draft = append([]rune(nil), self.line...)
+	}
+	self.histIdx--
+	self.setLine([]rune(self.History[self.histIdx]))
+}
+
+func (self *LineEditor) historyNext() {
+	if self.histIdx >= len(self.History) {
+		return
+	}
+	self.histIdx++
+	if self.histIdx == len(self.History) {
+		self.setLine(self.draft)
+	} else {
+		self.setLine([]rune(self.History[self.histIdx]))
+	}
+}
+
+func (self *LineEditor) setLine(line []rune) {
+	self.line = append(self.line[:0], line...)
+	self.pos = len(self.line)
+}
+
+// complete applies tab completion at the cursor
+//
+// A single candidate replaces the current word outright. Multiple candidates
+// extend the word to their longest common prefix or, failing that, are listed
+// beneath the prompt.
+func (self *LineEditor) complete() {
+	if self.Complete == nil {
+		return
+	}
+
+	candidates, start := self.Complete(self.line, self.pos)
+	if len(candidates) == 0 {
+		return
+	}
+
+	replacement := candidates[0]
+	if len(candidates) == 1 {
+		replacement += " "
+	} else {
+		for _, c := range candidates[1:] {
+			replacement = commonPrefix(replacement, c)
+		}
+		if len([]rune(replacement)) <= self.pos-start {
+			fmt.Fprint(self.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+			return
+		}
+	}
+
+	tail := append([]rune(nil), self.line[self.pos:]...)
+	self.line = append(append(self.line[:start], []rune(replacement)...), tail...)
+	self.pos = start + len([]rune(replacement))
+}
+
+// refresh redraws the prompt and line, placing the cursor
+func (self *LineEditor) refresh() {
+	cursor := displayWidth(self.Prompt + string(self.line[:self.pos]))
+	fmt.Fprintf(self.out, "\r%s%s\x1b[K\r", self.Prompt, string(self.line))
+	if cursor > 0 {
+		fmt.Fprintf(self.out, "\x1b[%dC", cursor)
+	}
+}
+
+func commonPrefix(a, b string) string {
+	ar, br := []rune(a), []rune(b)
+	n := 0
+	for n < len(ar) && n < len(br) && ar[n] == br[n] {
+		n++
+	}
+	return string(ar[:n])
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/illbjorn/echo"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+func ListExtensions(extDirs []string, format Format) error {
+	installed, err := installedIn(extDirs)
+	if err != nil {
+		return err
+	}
+
+	return Render(os.Stdout, format, installed)
+}
+
+// installedIn produces the extensions installed across all of `extDirs`
+func installedIn(extDirs []string) ([]InstalledExtension, error) {
+	var installed []InstalledExtension
+	for _, extDir := range extDirs {
+		// An editor with nothing installed yet may not have an extension
+		// directory at all
+		found, err := InstalledExtensions(extDir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		installed = append(installed, found...)
+	}
+	return installed, nil
+}
+
+// OutdatedExtension is an installed extension for which the gallery holds a
+// newer version
+type OutdatedExtension struct {
+	Extension string `json:"extension"`
+	Installed string `json:"installed"`
+	Latest    string `json:"latest"`
+	Path      string `json:"path"`
+
+	// Changelog holds the changes between the installed and latest versions,
+	// if requested
+	Changelog []ChangelogSection `json:"changelog,omitempty"`
+}
+
+func (OutdatedExtension) Columns() []string {
+	return []string{"Extension", "Installed", "Latest", "Path"}
+}
+
+func (self OutdatedExtension) Row() []string {
+	return []string{self.Extension, self.Installed, self.Latest, self.Path}
+}
+
+// OutdatedOptions configures OutdatedExtensions
+type OutdatedOptions struct {
+	// Jobs is the number of lookups run at a time
+	Jobs int
+
+	// Changelog includes the changelog sections between the installed and
+	// latest versions of each outdated extension
+	Changelog bool
+
+	// Resolve selects the latest version of each extension, skipping
+	// pre-releases and versions the targeted engine doesn't support as
+	// configured
+	Resolve ResolveOptions
+}
+
+func OutdatedExtensions(
+	ctx context.Context,
+	g *Galleries,
+	extDirs []string,
+	format Format,
+	opts OutdatedOptions,
+) error {
+	installed, err := installedIn(extDirs)
+	if err != nil {
+		return err
+	}
+
+	outdated, err := FindOutdated(ctx, g, installed, opts)
+
+	// Changelogs don't fit in table cells, so are written after the table
+	if opts.Changelog && format == FormatTable {
+		var sections []ChangelogSection
+		for _, ext := range outdated {
+			sections = append(sections, ext.Changelog...)
+		}
+		renderErr := Render(os.Stdout, format, outdated)
+		if len(sections) > 0 {
+			fmt.Println()
+			renderErr = errors.Join(renderErr, printChangelog(os.Stdout, sections))
+		}
+		return errors.Join(renderErr, err)
+	}
+
+	return errors.Join(Render(os.Stdout, format, outdated), err)
+}
+
+// FindOutdated produces those of `installed` for which the gallery holds a
+// later version, resolved as configured by `opts`
+func FindOutdated(
+	ctx context.Context,
+	g *Galleries,
+	installed []InstalledExtension,
+	opts OutdatedOptions,
+) ([]OutdatedExtension, error) {
+	pool := NewPool(ctx, opts.Jobs)
+
+	// Look up the latest version (and changes since the installed version) of
+	// each installed extension
+	latest := make([]string, len(installed))
+	changelogs := make([][]ChangelogSection, len(installed))
+	for i, ext := range installed {
+		pool.Go(func(ctx context.Context) error {
+			resolved, err := ResolveExtension(ctx, g, ext.Publisher, ext.Name, "", opts.Resolve)
+			// Extensions without a version we may install are left as they are
+			if errors.Is(err, ErrNoVersion) {
+				echo.Debugf("No candidate update for [%s]: %s.", ext.ID(), err)
+				return nil
+			} else if err != nil {
+				return err
+			}
+			latest[i] = resolved.Version
+
+			if !opts.Changelog || compareVersions(ext.Version, latest[i]) >= 0 {
+				return nil
+			}
+			changelogs[i], err = ExtensionChangelog(ctx, g, resolved, ext.Version)
+			// Not every extension publishes a changelog
+			if errors.Is(err, gallery.ErrNotFound) {
+				echo.Debugf("No changelog for [%s].", ext.ID())
+				return nil
+			}
+			return err
+		})
+	}
+
+	// Wait for all jobs to complete
+	errs := pool.Wait()
+
+	var outdated []OutdatedExtension
+	for i, ext := range installed {
+		if latest[i] == "" || compareVersions(ext.Version, latest[i]) >= 0 {
+			continue
+		}
+		outdated = append(outdated, OutdatedExtension{
+			Extension: ext.ID(),
+			Installed: ext.Version,
+			Latest:    latest[i],
+			Path:      ext.Path,
+			Changelog: changelogs[i],
+		})
+	}
+
+	// Extensions missing from the gallery (ex: side-loaded) are not considered
+	// failures
+	for i, err := range errs {
+		if errors.Is(err, gallery.ErrNotFound) {
+			errs[i] = nil
+		}
+	}
+
+	return outdated, errors.Join(errs...)
+}
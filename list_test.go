@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/illbjorn/vsx/gallery"
+	"github.com/illbjorn/zest"
+)
+
+// testOutdatedVersions are the versions of `ourcorp.tools` the outdated test
+// gallery reports: a pre-release, then a version requiring a later editor
+// than the test's
+var testOutdatedVersions = fmt.Sprintf(`[
+  {"version": "2.0.0", "properties": [{"key": %[1]q, "value": "true"}, {"key": %[2]q, "value": "^1.90.0"}]},
+  {"version": "1.5.0", "properties": [{"key": %[2]q, "value": "^1.99.0"}]},
+  {"version": "1.2.0", "properties": [{"key": %[2]q, "value": "^1.90.0"}]},
+  {"version": "1.0.0"}
+]`, gallery.PropertyPreRelease, gallery.PropertyEngine)
+
+const testOutdatedChangelog = `# Changelog
+
+## 2.0.0
+- Pre-release
+
+## 1.5.0
+- New editor API
+
+## 1.2.0
+- Fixes
+
+## 1.0.0
+- Initial release
+`
+
+// testOutdatedGallery starts a gallery serving testOutdatedVersions for every
+// extension except those of publisher `missing`, recording the paths of the
+// changelogs requested
+func testOutdatedGallery(t *testing.T, changelogs *[]string) *Galleries {
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			if strings.Contains(string(body), `"missing.`) {
+				_, _ = io.WriteString(w, `{"results": [{"extensions": []}]}`)
+				return
+			}
+			_, _ = fmt.Fprintf(w,
+				`{"results": [{"extensions": [{"publisher": {"publisherName": "ourcorp"}, "extensionName": "tools", "versions": %s}]}]}`,
+				testOutdatedVersions,
+			)
+			return
+		}
+
+		mu.Lock()
+		*changelogs = append(*changelogs, r.URL.Path)
+		mu.Unlock()
+		_, _ = io.WriteString(w, testOutdatedChangelog)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	g, err := NewGalleries(&Config{GalleryScheme: u.Scheme, GalleryHost: u.Host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestFindOutdated(t *testing.T) {
+	z := zest.New(t)
+
+	extDir := t.TempDir()
+	for dir, manifest := range map[string]string{
+		"ourcorp.tools-1.0.0": `{"name": "tools", "publisher": "ourcorp", "version": "1.0.0"}`,
+		"missing.gone-1.0.0":  `{"name": "gone", "publisher": "missing", "version": "1.0.0"}`,
+	} {
+		z.Assert(os.Mkdir(filepath.Join(extDir, dir), 0o755) == nil, "failed to create [%s]", dir)
+		err := os.WriteFile(filepath.Join(extDir, dir, "package.json"), []byte(manifest), 0o644)
+		z.Assert(err == nil, "failed to write [%s] package.json: %s", dir, err)
+	}
+	installed, err := installedIn([]string{extDir})
+	z.Assert(err == nil, "unexpected error: %s", err)
+
+	tests := []struct {
+		name       string
+		resolve    ResolveOptions
+		wantLatest string
+	}{
+		{"unchecked engine", ResolveOptions{}, "1.5.0"},
+		{"engine", ResolveOptions{Engine: "1.95.0"}, "1.2.0"},
+		{"engine with pre-releases", ResolveOptions{Engine: "1.95.0", PreRelease: true}, "2.0.0"},
+		{"no supported update", ResolveOptions{Engine: "1.80.0"}, ""},
+	}
+	for _, test := range tests {
+		var changelogs []string
+		g := testOutdatedGallery(t, &changelogs)
+
+		outdated, err := FindOutdated(context.Background(), g, installed, OutdatedOptions{
+			Jobs:      2,
+			Changelog: true,
+			Resolve:   test.resolve,
+		})
+		z.Assert(err == nil, "[%s]: unexpected error: %s", test.name, err)
+
+		if test.wantLatest == "" {
+			z.Assert(len(outdated) == 0, "[%s]: expected nothing outdated, got %+v", test.name, outdated)
+			z.Assert(len(changelogs) == 0, "[%s]: expected no changelogs fetched, got %q", test.name, changelogs)
+			continue
+		}
+		z.Assert(len(outdated) == 1, "[%s]: expected 1 outdated extension, got %+v", test.name, outdated)
+		got := outdated[0]
+		z.Assert(got.Latest == test.wantLatest, "[%s]: expected latest [%s], got [%s]", test.name, test.wantLatest, got.Latest)
+
+		// The changelog is that of the resolved version, up to it
+		z.Assert(
+			len(changelogs) == 1 && strings.Contains(changelogs[0], "/"+test.wantLatest+"/"),
+			"[%s]: expected the changelog of [%s], got %q", test.name, test.wantLatest, changelogs,
+		)
+		last := got.Changelog[0].Version
+		z.Assert(last == test.wantLatest, "[%s]: expected changes up to [%s], got [%s]", test.name, test.wantLatest, last)
+	}
+}
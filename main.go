@@ -1,30 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/illbjorn/argv"
 	"github.com/illbjorn/echo"
-	"github.com/illbjorn/vsx/gallery"
 )
 
 const (
 	app = "vsx"
 )
 
-// TODO: Implement `config` subcommand to manually persist configuration values
 // TODO: Implement signature verification of downloaded VSIX files (PKCS #1 / v1.5)
 // TODO: Implement `update` subcommand
 // TODO: Implement `backup` and `restore` subcommands
-// TODO: Implement timeout support (init contexts, pass with timeout to CMD handlers)
-// TODO: Implement `list` subcommand
 
 func main() {
 	// Parse command-line args
 	//
 	// Some of these values (ex: `extension-dir`) can be persistent configuration
 	// items. Considering this, further down we'll merge these values into the
-	// `Config` instance and persist them.
+	// `Config` instance (and persist them, if `--save` was provided).
 	cmd, err := argv.Parse(os.Args[1:])
 	if err != nil {
 		echo.Fatalf("Failed to parse input: %s.", err)
@@ -45,30 +45,50 @@ func main() {
 
 	// Prepare configuration
 	//
-	// 1. Load the config file
-	// 2. Load any environment configuration, clobber config file values with
-	//    these
-	// 3. Clobber any file/env configuration values with command-line arg values
-	cfg, err := LoadConfigFile()
+	// Defaults, the global config file, the project config file, environment
+	// variables and command-line flags, each clobbering the values before it
+	cfg, err := LoadConfig(cmd)
 	if err != nil {
-		echo.Info("No configuration found, proceeding with defaults.")
-		cfg = new(Config)
+		echo.Fatalf("ERROR: %s.", err)
 	}
-	cfg = LoadConfigEnv(cfg)
-	cfg = MergeInputs(cfg, cmd)
 
-	// Save the config now to reflect any command-line updates
-	err = SaveConfigFile(cfg)
-	if err != nil {
+	// Persist any command-line configuration values if asked to
+	if err := SaveInputs(cmd); err != nil {
 		echo.Errorf("Failed to save config: %s.", err)
 	}
 
-	// Init the Gallery client
-	g := gallery.New(cfg.GalleryScheme, cfg.GalleryHost)
+	// Init the Gallery clients
+	g, err := NewGalleries(cfg)
+	if err != nil {
+		echo.Fatalf("ERROR: %s.", err)
+	}
+
+	// Init the root context, canceled on SIGTERM
+	//
+	// SIGINT (Ctrl-C) cancels individual commands instead, so in the REPL it
+	// abandons the running command rather than the whole session.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
 
 	// Exec the command
-	err = Run(g, cfg, cmd)
-	if err != nil {
+	//
+	// With no command (or `shell`) we drop into the REPL instead
+	if cmd.Name == "" || cmd.Name == cmdShell {
+		err = RunREPL(ctx, g, cfg)
+	} else {
+		cmdCtx, stopCmd := signal.NotifyContext(ctx, os.Interrupt)
+		err = Run(cmdCtx, g, cfg, cmd)
+		stopCmd()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		stop()
+		echo.Fatal("Canceled.")
+	case errors.Is(err, context.DeadlineExceeded):
+		stop()
+		echo.Fatal("Timed out.")
+	case err != nil:
+		stop()
 		echo.Fatalf("ERROR: %s.", err)
 	}
 }
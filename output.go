@@ -0,0 +1,359 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/illbjorn/argv"
+)
+
+// Format is a supported output format, selected with [`--output-format`]
+type Format = string
+
+const (
+	FormatTable Format = "table"
+	FormatJSON  Format = "json"
+	FormatJSONL Format = "jsonl"
+	FormatCSV   Format = "csv"
+	FormatYAML  Format = "yaml"
+)
+
+var (
+	ErrFormat = fmt.Errorf("unknown output format, expected one of: table, json, jsonl, csv, yaml")
+)
+
+// ParseFormat produces the output format requested by `cmd`, defaulting to
+// FormatTable
+//
+// `--json` is shorthand for `--output-format json`.
+func ParseFormat(cmd argv.Command) (Format, error) {
+	v, ok := cmd.Flag(flagOutputFormat)
+	if !ok {
+		if _, ok := cmd.Flag(flagJSON); ok {
+			return FormatJSON, nil
+		}
+		return FormatTable, nil
+	}
+
+	switch format := strings.ToLower(v[0]); format {
+	case FormatTable, FormatJSON, FormatJSONL, FormatCSV, FormatYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("--%s [%s]: %w", flagOutputFormat, v[0], ErrFormat)
+	}
+}
+
+// Record is a single structured command result
+//
+// In the machine-readable formats, a record is encoded by its `json` struct
+// tags. In table form, it's rendered as a single row.
+type Record interface {
+	// Columns produces the table headers for the record
+	Columns() []string
+
+	// Row produces the record's table values, ordered to match Columns
+	Row() []string
+}
+
+// Render writes `records` to `w` in the provided format
+//
+// JSON is written as a single array, JSONL as one object per line, CSV as a
+// header row followed by one row per record and YAML as a single sequence.
+func Render[T Record](w io.Writer, format Format, records []T) error {
+	switch format {
+	default:
+		return fmt.Errorf("%w: [%s]", ErrFormat, format)
+
+	case FormatTable:
+		var columns []string
+		if len(records) > 0 {
+			columns = records[0].Columns()
+		} else {
+			var zero T
+			columns = zero.Columns()
+		}
+		rows := make([][]string, len(records))
+		for i, record := range records {
+			rows[i] = record.Row()
+		}
+		return renderTable(w, columns, rows)
+
+	case FormatJSON:
+		// Encode empty results as `[]` rather than `null`
+		if records == nil {
+			records = []T{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case FormatCSV:
+		var zero T
+		cw := csv.NewWriter(w)
+		if err := cw.Write(fieldNames(reflect.TypeOf(zero))); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := cw.Write(fieldValues(reflect.ValueOf(record))); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case FormatYAML:
+		if len(records) == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		var b strings.Builder
+		writeYAML(&b, reflect.ValueOf(records), 0)
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CSV
+
+// fieldNames produces the `json` names of each exported field of struct type
+// `t`
+func fieldNames(t reflect.Type) []string {
+	var names []string
+	for _, field := range structFields(t) {
+		names = append(names, field.name)
+	}
+	return names
+}
+
+// fieldValues produces the string form of each exported field of struct `v`
+//
+// Scalars are formatted directly, anything else is JSON-encoded into the cell.
+func fieldValues(v reflect.Value) []string {
+	var values []string
+	for _, field := range structFields(v.Type()) {
+		values = append(values, scalarString(v.Field(field.index)))
+	}
+	return values
+}
+
+func scalarString(v reflect.Value) string {
+	if s, ok := scalar(v); ok {
+		return s
+	}
+	data, _ := json.Marshal(v.Interface())
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// YAML
+//
+// The YAML emitted here covers exactly what our records need: structs, maps
+// with string keys, slices and scalars. Strings are always quoted to sidestep
+// YAML's implicit typing, as are keys unless plainly safe.
+
+var (
+	// yamlPlainKey matches mapping keys safe to write unquoted
+	yamlPlainKey = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*$`)
+
+	// yamlReserved are the plain scalars YAML reads as booleans or null
+	yamlReserved = []string{"true", "false", "yes", "no", "on", "off", "y", "n", "null", "~"}
+)
+
+func writeYAML(b *strings.Builder, v reflect.Value, indent int) {
+	v = indirect(v)
+	pad := strings.Repeat("  ", indent)
+
+	switch {
+	case !v.IsValid():
+		b.WriteString(pad + "null\n")
+
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if v.Len() == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for i := range v.Len() {
+			item := indirect(v.Index(i))
+			if s, ok := scalarYAML(item); ok {
+				b.WriteString(pad + "- " + s + "\n")
+				continue
+			}
+			// Write the nested value one level deeper, then swap the leading
+			// indent of its first line for the sequence marker
+			var nested strings.Builder
+			writeYAML(&nested, item, indent+1)
+			b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+		}
+
+	case v.Kind() == reflect.Map:
+		if v.Len() == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		for _, key := range keys {
+			writeYAMLField(b, fmt.Sprint(key.Interface()), v.MapIndex(key), indent)
+		}
+
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		for _, field := range structFields(v.Type()) {
+			value := v.Field(field.index)
+			if field.omitEmpty && value.IsZero() {
+				continue
+			}
+			writeYAMLField(b, field.name, value, indent)
+		}
+
+	default:
+		s, _ := scalarYAML(v)
+		b.WriteString(pad + s + "\n")
+	}
+}
+
+func writeYAMLField(b *strings.Builder, key string, v reflect.Value, indent int) {
+	pad := strings.Repeat("  ", indent)
+	v = indirect(v)
+
+	key = yamlKey(key)
+
+	if s, ok := scalarYAML(v); ok {
+		b.WriteString(pad + key + ": " + s + "\n")
+		return
+	}
+
+	// Empty collections are written inline
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		if v.Kind() == reflect.Map {
+			b.WriteString(pad + key + ": {}\n")
+		} else {
+			b.WriteString(pad + key + ": []\n")
+		}
+		return
+	}
+
+	b.WriteString(pad + key + ":\n")
+	writeYAML(b, v, indent+1)
+}
+
+// yamlKey produces mapping key `key` as written: plain when it can't be read
+// as anything else and quoted otherwise (ex: empty keys, `a: b`, `-x`, `null`,
+// `1.0`)
+func yamlKey(key string) string {
+	_, errInt := strconv.ParseInt(key, 0, 64)
+	_, errFloat := strconv.ParseFloat(key, 64)
+	if yamlPlainKey.MatchString(key) &&
+		!slices.Contains(yamlReserved, strings.ToLower(key)) &&
+		errInt != nil && errFloat != nil {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func scalarYAML(v reflect.Value) (string, bool) {
+	if !v.IsValid() {
+		return "null", true
+	}
+	s, ok := scalar(v)
+	if !ok {
+		return "", false
+	}
+	if v.Kind() == reflect.String || v.Type() == timeType {
+		return strconv.Quote(s), true
+	}
+	return s, true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Reflection Helpers
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// structFields produces the exported, non-ignored fields of struct type `t`
+// named per their `json` tags
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{
+			index:     i,
+			name:      name,
+			omitEmpty: strings.Contains(opts, "omitempty"),
+		})
+	}
+	return fields
+}
+
+// scalar produces the string form of `v` if it's a scalar (or a `time.Time`)
+func scalar(v reflect.Value) (string, bool) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return "", true
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	}
+
+	return "", false
+}
+
+// indirect dereferences pointers and interfaces, producing an invalid
+// `reflect.Value` for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
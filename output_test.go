@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+type testRecord struct {
+	Name  string            `json:"name"`
+	Count int               `json:"count"`
+	Tags  []string          `json:"tags"`
+	Meta  map[string]string `json:"meta,omitempty"`
+}
+
+func (testRecord) Columns() []string {
+	return []string{"Name", "Count"}
+}
+
+func (self testRecord) Row() []string {
+	return []string{self.Name, strconv.Itoa(self.Count)}
+}
+
+var testRecords = []testRecord{
+	{
+		Name:  "errorlens",
+		Count: 3,
+		Tags:  []string{"linters", "a: b"},
+		Meta: map[string]string{
+			"plain":        "value",
+			"":             "empty",
+			"key: value":   "colon",
+			"# comment":    "hash",
+			"with space":   "space",
+			"-dash":        "dash",
+			"*alias":       "star",
+			"true":         "bool",
+			"1.0":          "number",
+			"quote\"inner": "quote",
+		},
+	},
+	{Name: "python", Count: 0, Tags: []string{}},
+}
+
+// testRecordsYAML is testRecords in YAML, keys sorted
+const testRecordsYAML = `- name: "errorlens"
+  count: 3
+  tags:
+    - "linters"
+    - "a: b"
+  meta:
+    "": "empty"
+    "# comment": "hash"
+    "*alias": "star"
+    "-dash": "dash"
+    "1.0": "number"
+    "key: value": "colon"
+    plain: "value"
+    "quote\"inner": "quote"
+    "true": "bool"
+    "with space": "space"
+- name: "python"
+  count: 0
+  tags: []
+`
+
+func TestRender(t *testing.T) {
+	z := zest.New(t)
+
+	tests := []struct {
+		format  Format
+		records []testRecord
+		want    string
+		decode  func(string) ([]testRecord, error)
+	}{
+		{format: FormatJSON, records: testRecords, decode: decodeJSON},
+		{format: FormatJSON, records: nil, want: "[]\n"},
+		{format: FormatJSONL, records: testRecords, decode: decodeJSONL},
+		{format: FormatJSONL, records: nil, want: ""},
+		{format: FormatCSV, records: testRecords, decode: decodeCSV},
+		{format: FormatCSV, records: nil, want: "name,count,tags,meta\n"},
+		{format: FormatYAML, records: testRecords, want: testRecordsYAML},
+		{format: FormatYAML, records: nil, want: "[]\n"},
+		{format: FormatYAML, records: []testRecord{}, want: "[]\n"},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		err := Render(&b, test.format, test.records)
+		z.Assert(err == nil, "[%s]: unexpected error: %s", test.format, err)
+
+		if test.decode == nil {
+			z.Assert(b.String() == test.want, "[%s]: expected:\n%s\ngot:\n%s", test.format, test.want, b.String())
+			continue
+		}
+		got, err := test.decode(b.String())
+		z.Assert(err == nil, "[%s]: failed to decode output: %s", test.format, err)
+		z.Assert(reflect.DeepEqual(got, test.records), "[%s]: expected %+v, got %+v", test.format, test.records, got)
+	}
+}
+
+func decodeJSON(s string) ([]testRecord, error) {
+	var records []testRecord
+	err := json.Unmarshal([]byte(s), &records)
+	return records, err
+}
+
+func decodeJSONL(s string) ([]testRecord, error) {
+	var records []testRecord
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		var record testRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+func decodeCSV(s string) ([]testRecord, error) {
+	rows, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []testRecord
+	for _, row := range rows[1:] {
+		record := testRecord{Name: row[0]}
+		if record.Count, err = strconv.Atoi(row[1]); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(row[2]), &record.Tags); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(row[3]), &record.Meta); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	cmdPolicyCheck = "check"
+)
+
+// Policy actions
+const (
+	policyAllow = "allow"
+	policyDeny  = "deny"
+)
+
+var (
+	ErrPolicy       = fmt.Errorf("invalid policy file")
+	ErrPolicyDenied = fmt.Errorf("denied by policy")
+)
+
+// Policy decides which extensions may be installed and downloaded
+//
+// Rules are evaluated in order, the first matching rule deciding. Extensions
+// matching no rule are decided by Default.
+type Policy struct {
+	// Default is the action taken for extensions matching no rule: `allow`
+	// (the default) or `deny`
+	Default string `json:"default,omitempty"`
+
+	Rules []PolicyRule `json:"rules"`
+
+	// path is the policy file the policy was read from
+	path string
+}
+
+// PolicyRule matches extensions satisfying every one of its conditions, unset
+// conditions matching anything
+type PolicyRule struct {
+	// Action is taken for matching extensions: `allow` or `deny`
+	Action string `json:"action"`
+
+	// Publisher is a glob matched (case-insensitively) against the publisher
+	Publisher string `json:"publisher,omitempty"`
+
+	// Extension is a glob matched (case-insensitively) against the extension
+	// identifier in `publisher.name` form (ex: `ms-python.*`)
+	Extension string `json:"extension,omitempty"`
+
+	// Version is a version range (ex: `>=1.0 <2.0`, see ParseConstraint)
+	Version string `json:"version,omitempty"`
+
+	// Verified matches extensions whose publisher is (or isn't) verified by
+	// the gallery, extensions missing from the gallery being unverified
+	Verified *bool `json:"verified,omitempty"`
+
+	// License holds globs matched (case-insensitively) against the license of
+	// `package.json` (ex: `GPL-*`), any one matching
+	License []string `json:"license,omitempty"`
+
+	// Native matches extensions bundling (or not bundling) native binaries
+	Native *bool `json:"native,omitempty"`
+
+	// Reason explains the rule in violation reports
+	Reason string `json:"reason,omitempty"`
+
+	constraint Constraint
+}
+
+// PolicySubject describes an extension for policy evaluation
+type PolicySubject struct {
+	Publisher string
+	Name      string
+	Version   string
+	Verified  bool
+	License   string
+	Native    bool
+}
+
+// PolicyDecision is the outcome of evaluating a policy
+type PolicyDecision struct {
+	Allowed bool
+
+	// Rule is the index of the deciding rule, or -1 if decided by default
+	Rule int
+
+	Reason string
+}
+
+func (self PolicyDecision) Error() string {
+	if self.Reason == "" {
+		return ErrPolicyDenied.Error()
+	}
+	return ErrPolicyDenied.Error() + ": " + self.Reason
+}
+
+func (self PolicyDecision) Unwrap() error {
+	return ErrPolicyDenied
+}
+
+// LoadPolicy reads the policy file configured by `cfg`, producing nil if none
+// is configured
+func LoadPolicy(cfg *Config) (*Policy, error) {
+	if cfg.PolicyFile == "" {
+		return nil, nil
+	}
+	return ReadPolicy(cfg.PolicyFile)
+}
+
+// ReadPolicy reads and validates the policy file at `file`, in JSON or (with a
+// `.toml` extension) TOML
+func ReadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w [%s]: %w", ErrPolicy, file, err)
+	}
+
+	// As with project config files, TOML is decoded generically and
+	// re-encoded to share the JSON decoding
+	if filepath.Ext(file) == ".toml" {
+		values, err := decodeTOML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w [%s]: %w", ErrPolicy, file, err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return nil, fmt.Errorf("%w [%s]: %w", ErrPolicy, file, err)
+		}
+	}
+
+	self := &Policy{path: file}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(self); err != nil {
+		return nil, fmt.Errorf("%w [%s]: %w", ErrPolicy, file, err)
+	}
+
+	switch self.Default {
+	case "":
+		self.Default = policyAllow
+	case policyAllow, policyDeny:
+	default:
+		return nil, fmt.Errorf("%w [%s]: default: expected `allow` or `deny`, got [%s]", ErrPolicy, file, self.Default)
+	}
+
+	for i := range self.Rules {
+		rule := &self.Rules[i]
+		if rule.Action != policyAllow && rule.Action != policyDeny {
+			return nil, fmt.Errorf("%w [%s]: rule %d: action: expected `allow` or `deny`, got [%s]", ErrPolicy, file, i+1, rule.Action)
+		}
+		for _, pattern := range append([]string{rule.Publisher, rule.Extension}, rule.License...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%w [%s]: rule %d: pattern [%s]: %w", ErrPolicy, file, i+1, pattern, err)
+			}
+		}
+		if rule.Version != "" {
+			if rule.constraint, err = ParseConstraint(rule.Version); err != nil {
+				return nil, fmt.Errorf("%w [%s]: rule %d: %w", ErrPolicy, file, i+1, err)
+			}
+		}
+	}
+
+	return self, nil
+}
+
+// NeedsPackage reports whether evaluating the policy needs an extension's
+// package content (its license or native binaries)
+func (self *Policy) NeedsPackage() bool {
+	for _, rule := range self.Rules {
+		if len(rule.License) > 0 || rule.Native != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsVerified reports whether evaluating the policy needs the verification
+// status of an extension's publisher
+func (self *Policy) NeedsVerified() bool {
+	for _, rule := range self.Rules {
+		if rule.Verified != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides whether `subject` is allowed
+func (self *Policy) Evaluate(subject PolicySubject) PolicyDecision {
+	for i, rule := range self.Rules {
+		if rule.match(subject) {
+			return PolicyDecision{
+				Allowed: rule.Action == policyAllow,
+				Rule:    i,
+				Reason:  cmp.Or(rule.Reason, fmt.Sprintf("rule %d of [%s]", i+1, self.path)),
+			}
+		}
+	}
+	return PolicyDecision{
+		Allowed: self.Default == policyAllow,
+		Rule:    -1,
+		Reason:  fmt.Sprintf("default of [%s]", self.path),
+	}
+}
+
+func (self PolicyRule) match(subject PolicySubject) bool {
+	if !globMatch(self.Publisher, subject.Publisher) {
+		return false
+	}
+	if !globMatch(self.Extension, subject.Publisher+"."+subject.Name) {
+		return false
+	}
+	if self.constraint != nil {
+		v, err := ParseSemver(subject.Version)
+		if err != nil || !self.constraint.Match(v) {
+			return false
+		}
+	}
+	if self.Verified != nil && *self.Verified != subject.Verified {
+		return false
+	}
+	if self.Native != nil && *self.Native != subject.Native {
+		return false
+	}
+	if len(self.License) > 0 {
+		matched := false
+		for _, pattern := range self.License {
+			matched = matched || globMatch(pattern, subject.License)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// globMatch reports whether `s` matches glob `pattern` case-insensitively, an
+// empty pattern matching anything
+func globMatch(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return ok
+}
+
+// CheckPackage evaluates the policy against fetched package `zr` of `ext`,
+// producing a PolicyDecision error if denied
+//
+// A nil policy allows everything.
+func (self *Policy) CheckPackage(ext ResolvedExtension, zr *zip.Reader) error {
+	if self == nil {
+		return nil
+	}
+
+	subject := PolicySubject{
+		Publisher: ext.Publisher,
+		Name:      ext.Name,
+		Version:   ext.Version,
+		Verified:  ext.Verified,
+	}
+	if self.NeedsPackage() {
+		pkg, err := ReadVSIX(zr)
+		if err != nil {
+			return err
+		}
+		subject.License, subject.Native = pkg.Manifest.License, pkg.native()
+	}
+
+	if decision := self.Evaluate(subject); !decision.Allowed {
+		return fmt.Errorf("[%s]: %w", ext, decision)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Policy Check
+
+// PolicyResult is the policy decision for a single installed extension
+type PolicyResult struct {
+	Extension string `json:"extension"`
+	Version   string `json:"version"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	Path      string `json:"path"`
+}
+
+func (PolicyResult) Columns() []string {
+	return []string{"Extension", "Version", "Action", "Reason", "Path"}
+}
+
+func (self PolicyResult) Row() []string {
+	return []string{self.Extension, self.Version, self.Action, self.Reason, self.Path}
+}
+
+// PolicyCommand runs `vsx policy` subcommands
+func PolicyCommand(ctx context.Context, g *Galleries, cfg *Config, format Format, cmd argv.Command) error {
+	if len(cmd.Args) == 0 || cmd.Args[0] != cmdPolicyCheck {
+		return UsageError("Expected a policy subcommand: %s.", cmdPolicyCheck)
+	}
+
+	policy, err := LoadPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return UsageError("No policy file configured (see the 'policy_file' config key).")
+	}
+	extDirs, err := ExtensionDirs(cfg)
+	if err != nil {
+		return err
+	}
+
+	results, err := CheckInstalled(ctx, g, policy, extDirs)
+	return errors.Join(Render(os.Stdout, format, results), err)
+}
+
+// CheckInstalled evaluates `policy` against every extension installed in
+// `extDirs`, producing an ErrPolicyDenied error if any violate it
+func CheckInstalled(ctx context.Context, g *Galleries, policy *Policy, extDirs []string) ([]PolicyResult, error) {
+	installed, err := installedIn(extDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []PolicyResult
+	var errs []error
+	violations := 0
+	for _, ext := range installed {
+		subject := PolicySubject{
+			Publisher: ext.Publisher,
+			Name:      ext.Name,
+			Version:   ext.Version,
+		}
+
+		if policy.NeedsVerified() {
+			meta, err := g.GetExtensionMeta(ctx, ext.Publisher, ext.Name)
+			switch {
+			case err == nil:
+				subject.Verified = meta.Publisher.Flags.Verified || meta.Publisher.DomainVerified
+			case errors.Is(err, gallery.ErrNotFound):
+				// Side-loaded extensions are unverified
+			default:
+				errs = append(errs, fmt.Errorf("failed to look up [%s]: %w", ext.ID(), err))
+				continue
+			}
+		}
+		if policy.NeedsPackage() {
+			pkg, err := readInstalledPackage(ext)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			subject.License, subject.Native = pkg.Manifest.License, pkg.native()
+		}
+
+		decision := policy.Evaluate(subject)
+		result := PolicyResult{
+			Extension: ext.ID(),
+			Version:   ext.Version,
+			Action:    policyAllow,
+			Reason:    decision.Reason,
+			Path:      ext.Path,
+		}
+		if !decision.Allowed {
+			result.Action = policyDeny
+			violations++
+		}
+		results = append(results, result)
+	}
+
+	if violations > 0 {
+		errs = append(errs, fmt.Errorf("%d installed extension(s) %w", violations, ErrPolicyDenied))
+	}
+	return results, errors.Join(errs...)
+}
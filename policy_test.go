@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+const testPolicy = `{
+  "default": "deny",
+  "rules": [
+    {"action": "deny", "extension": "ourcorp.legacy", "version": "<2.0", "reason": "legacy is end of life"},
+    {"action": "deny", "native": true, "reason": "native binaries need review"},
+    {"action": "deny", "license": ["GPL-*", "AGPL-*"]},
+    {"action": "allow", "publisher": "OurCorp"},
+    {"action": "allow", "verified": true}
+  ]
+}`
+
+func TestPolicy(t *testing.T) {
+	z := zest.New(t)
+
+	path := filepath.Join(t.TempDir(), "policy.json")
+	err := os.WriteFile(path, []byte(testPolicy), fileModeRW)
+	z.Assert(err == nil, "failed to write policy: %s", err)
+	policy, err := ReadPolicy(path)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(policy.NeedsPackage() && policy.NeedsVerified(), "expected package and verification needs")
+
+	for _, tc := range []struct {
+		subject PolicySubject
+		allowed bool
+		rule    int
+	}{
+		{PolicySubject{Publisher: "ourcorp", Name: "tools", Version: "1.0.0"}, true, 3},
+		{PolicySubject{Publisher: "ourcorp", Name: "legacy", Version: "1.9.0"}, false, 0},
+		{PolicySubject{Publisher: "ourcorp", Name: "legacy", Version: "2.0.0"}, true, 3},
+		{PolicySubject{Publisher: "ourcorp", Name: "tools", Version: "1.0.0", Native: true}, false, 1},
+		{PolicySubject{Publisher: "ourcorp", Name: "tools", Version: "1.0.0", License: "gpl-3.0"}, false, 2},
+		{PolicySubject{Publisher: "usernamehw", Name: "errorlens", Version: "3.26.0", Verified: true}, true, 4},
+		{PolicySubject{Publisher: "someone", Name: "thing", Version: "1.0.0"}, false, -1},
+	} {
+		decision := policy.Evaluate(tc.subject)
+		z.Assert(
+			decision.Allowed == tc.allowed && decision.Rule == tc.rule,
+			"[%s.%s@%s]: expected allowed [%t] by rule [%d], got [%t] by rule [%d]",
+			tc.subject.Publisher, tc.subject.Name, tc.subject.Version,
+			tc.allowed, tc.rule, decision.Allowed, decision.Rule,
+		)
+	}
+
+	// Denied extensions are never installed
+	extDir := t.TempDir()
+	results, err := InstallExtensions(t.Context(), testPackageGallery(t), []string{extDir}, []string{"someone.tools"}, InstallOptions{
+		Jobs:   1,
+		Policy: policy,
+	})
+	z.Assert(errors.Is(err, ErrPolicyDenied), "expected [%v], got [%v]", ErrPolicyDenied, err)
+	z.Assert(results[0].Status == statusFailed, "expected a failed install, got [%s]", results[0].Status)
+	entries, _ := os.ReadDir(extDir)
+	z.Assert(len(entries) == 0, "expected nothing installed, got %d entries", len(entries))
+
+	// Installed extensions are evaluated by their package.json and files
+	for dir, files := range map[string]map[string]string{
+		"ourcorp.tools-1.0.0": {
+			"package.json": `{"publisher": "ourcorp", "name": "tools", "version": "1.0.0"}`,
+		},
+		"ourcorp.native-1.0.0": {
+			"package.json": `{"publisher": "ourcorp", "name": "native", "version": "1.0.0"}`,
+			"bin/helper":   "\x7fELF\x02\x01\x01",
+		},
+		"ourcorp.copyleft-1.0.0": {
+			"package.json": `{"publisher": "ourcorp", "name": "copyleft", "version": "1.0.0", "license": "GPL-3.0"}`,
+		},
+	} {
+		for name, content := range files {
+			path := filepath.Join(extDir, dir, filepath.FromSlash(name))
+			z.Assert(os.MkdirAll(filepath.Dir(path), 0o755) == nil, "failed to create [%s]", filepath.Dir(path))
+			z.Assert(os.WriteFile(path, []byte(content), fileModeRW) == nil, "failed to write [%s]", path)
+		}
+	}
+	checked, err := CheckInstalled(t.Context(), testPackageGallery(t), policy, []string{extDir})
+	z.Assert(errors.Is(err, ErrPolicyDenied), "expected [%v], got [%v]", ErrPolicyDenied, err)
+	actions := make(map[string]string)
+	for _, result := range checked {
+		actions[result.Extension] = result.Action
+	}
+	for ext, want := range map[string]string{
+		"ourcorp.tools":    policyAllow,
+		"ourcorp.native":   policyDeny,
+		"ourcorp.copyleft": policyDeny,
+	} {
+		z.Assert(actions[ext] == want, "[%s]: expected [%s], got [%s]", ext, want, actions[ext])
+	}
+
+	// Invalid policies
+	err = os.WriteFile(path, []byte(`{"rules": [{"action": "maybe"}]}`), fileModeRW)
+	z.Assert(err == nil, "failed to write policy: %s", err)
+	_, err = ReadPolicy(path)
+	z.Assert(errors.Is(err, ErrPolicy), "expected [%v], got [%v]", ErrPolicy, err)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/illbjorn/echo"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	// progressRedraw is the minimum interval between progress bar redraws
+	progressRedraw = 100 * time.Millisecond
+
+	// progressInterval is the minimum interval between progress events for a
+	// single transfer, when not attached to a terminal
+	progressInterval = 2 * time.Second
+
+	// progressNameWidth is the width of the name column of progress bars
+	progressNameWidth = 28
+
+	// progressBarWidth is the width of the bar itself
+	progressBarWidth = 20
+)
+
+// ProgressReporter reports the progress of concurrent transfers
+type ProgressReporter interface {
+	// Track begins tracking the transfer of `name`, producing the hook to
+	// attach to it with gallery.WithProgress
+	Track(name string) gallery.ProgressFunc
+
+	// Close finishes reporting, once every transfer has ended
+	Close()
+}
+
+// NewProgressReporter produces a reporter writing to `f`: progress bars when
+// `f` is a terminal, otherwise periodic events (JSON lines for the JSON output
+// formats, plain text for the rest)
+func NewProgressReporter(f *os.File, format Format) ProgressReporter {
+	if isTerminal(f) {
+		return &barProgress{w: f, width: terminalWidth(f)}
+	}
+	json := format == FormatJSON || format == FormatJSONL
+	return &eventProgress{w: f, json: json}
+}
+
+// transfer is the progress of a single tracked transfer
+type transfer struct {
+	name     string
+	start    time.Time
+	progress gallery.Progress
+}
+
+// rate produces the transfer's average rate in bytes per second
+func (self transfer) rate() float64 {
+	elapsed := time.Since(self.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(self.progress.Read) / elapsed
+}
+
+// eta produces the estimated time remaining, or -1 if unknown
+func (self transfer) eta() time.Duration {
+	rate := self.rate()
+	if self.progress.Total < 0 || rate <= 0 {
+		return -1
+	}
+	remaining := float64(self.progress.Total - self.progress.Read)
+	return time.Duration(remaining / rate * float64(time.Second)).Round(time.Second)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Progress Bars
+
+// barProgress renders a progress bar per transfer, redrawn in place
+type barProgress struct {
+	w     io.Writer
+	width int
+
+	mu        sync.Mutex
+	transfers []*transfer
+	drawn     int
+	lastDraw  time.Time
+}
+
+func (self *barProgress) Track(name string) gallery.ProgressFunc {
+	self.mu.Lock()
+	t := &transfer{name: name, start: time.Now(), progress: gallery.Progress{Total: -1}}
+	self.transfers = append(self.transfers, t)
+	self.mu.Unlock()
+
+	return func(p gallery.Progress) {
+		self.mu.Lock()
+		defer self.mu.Unlock()
+		t.progress = p
+		if p.Done || time.Since(self.lastDraw) >= progressRedraw {
+			self.draw()
+		}
+	}
+}
+
+func (self *barProgress) Close() {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.draw()
+}
+
+// draw redraws every bar, moving the cursor back over those drawn previously
+func (self *barProgress) draw() {
+	var b strings.Builder
+	if self.drawn > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", self.drawn)
+	}
+	for _, t := range self.transfers {
+		b.WriteString("\r\x1b[K")
+		b.WriteString(truncate(barLine(*t), self.width))
+		b.WriteByte('\n')
+	}
+	_, _ = io.WriteString(self.w, b.String())
+
+	self.drawn = len(self.transfers)
+	self.lastDraw = time.Now()
+}
+
+// barLine produces a single progress bar line
+//
+// Example: `ms-python.python  [=========>          ]  4.2 MiB / 9.1 MiB  1.3 MiB/s  ETA 4s`
+func barLine(t transfer) string {
+	name := truncate(t.name, progressNameWidth)
+	name += strings.Repeat(" ", progressNameWidth-displayWidth(name))
+
+	// Without a known total, the bar is left empty until done
+	filled := 0
+	switch {
+	case t.progress.Done:
+		filled = progressBarWidth
+	case t.progress.Total > 0:
+		filled = int(t.progress.Read * progressBarWidth / t.progress.Total)
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	return fmt.Sprintf("%s  [%s]  %s", name, bar, progressDetail(t))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Progress Events
+
+// eventProgress reports each transfer's progress periodically, as plain text
+// or JSON lines
+type eventProgress struct {
+	w    io.Writer
+	json bool
+
+	mu sync.Mutex
+}
+
+// ProgressEvent is a single progress update, as reported in JSON form
+type ProgressEvent struct {
+	Extension  string  `json:"extension"`
+	Read       int64   `json:"bytes"`
+	Total      int64   `json:"total_bytes"`
+	Rate       float64 `json:"bytes_per_second"`
+	ETASeconds float64 `json:"eta_seconds"`
+	Done       bool    `json:"done"`
+}
+
+func (self *eventProgress) Track(name string) gallery.ProgressFunc {
+	t := &transfer{name: name, start: time.Now(), progress: gallery.Progress{Total: -1}}
+	var last time.Time
+
+	// Each transfer's hook is only called from the goroutine performing it, so
+	// only writes are synchronized
+	return func(p gallery.Progress) {
+		t.progress = p
+		if !p.Done && time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+
+		self.mu.Lock()
+		defer self.mu.Unlock()
+		if !self.json {
+			if _, err := fmt.Fprintf(self.w, "[%s] %s.\n", t.name, progressDetail(*t)); err != nil {
+				echo.Debugf("Failed to write progress event: %s.", err)
+			}
+			return
+		}
+
+		event := ProgressEvent{
+			Extension:  t.name,
+			Read:       p.Read,
+			Total:      p.Total,
+			Rate:       t.rate(),
+			ETASeconds: -1,
+			Done:       p.Done,
+		}
+		if eta := t.eta(); eta >= 0 {
+			event.ETASeconds = eta.Seconds()
+		}
+		if err := json.NewEncoder(self.w).Encode(event); err != nil {
+			echo.Debugf("Failed to write progress event: %s.", err)
+		}
+	}
+}
+
+func (self *eventProgress) Close() {}
+
+////////////////////////////////////////////////////////////////////////////////
+// Formatting
+
+// progressDetail produces the size, rate and ETA of a transfer (ex:
+// `4.2 MiB / 9.1 MiB  1.3 MiB/s  ETA 4s`)
+func progressDetail(t transfer) string {
+	size := formatBytes(t.progress.Read)
+	if t.progress.Total >= 0 {
+		size += " / " + formatBytes(t.progress.Total)
+	}
+	rate := formatBytes(int64(t.rate())) + "/s"
+
+	switch eta := t.eta(); {
+	case t.progress.Done:
+		return fmt.Sprintf("%s  %s  done", size, rate)
+	case eta >= 0:
+		return fmt.Sprintf("%s  %s  ETA %s", size, rate, eta)
+	default:
+		return fmt.Sprintf("%s  %s", size, rate)
+	}
+}
+
+// formatBytes produces a human-friendly, binary-prefixed size (ex: `4.2 MiB`)
+//
+// Negative sizes (ex: size deltas) are formatted by magnitude, then signed.
+func formatBytes(n int64) string {
+	const unit = 1024
+	sign, size := "", uint64(n)
+	if n < 0 {
+		sign, size = "-", -size
+	}
+	if size < unit {
+		return fmt.Sprintf("%s%d B", sign, size)
+	}
+	div, exp := uint64(unit), 0
+	for m := size / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%s%.1f %ciB", sign, float64(size)/float64(div), "KMGTPE"[exp])
+}
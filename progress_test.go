@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/vsx/gallery"
+	"github.com/illbjorn/zest"
+)
+
+func TestEventProgress(t *testing.T) {
+	z := zest.New(t)
+
+	// Both forms are written to the reporter's writer
+	var text strings.Builder
+	track := (&eventProgress{w: &text}).Track("ourcorp.tools")
+	track(gallery.Progress{Read: 2048, Total: 2048, Done: true})
+	z.Assert(strings.HasPrefix(text.String(), "[ourcorp.tools] 2.0 KiB / 2.0 KiB"), "unexpected text event [%s]", text.String())
+
+	var lines strings.Builder
+	track = (&eventProgress{w: &lines, json: true}).Track("ourcorp.tools")
+	track(gallery.Progress{Read: 2048, Total: 2048, Done: true})
+	var event ProgressEvent
+	err := json.Unmarshal([]byte(lines.String()), &event)
+	z.Assert(err == nil, "failed to decode [%s]: %s", lines.String(), err)
+	z.Assert(event.Extension == "ourcorp.tools" && event.Read == 2048 && event.Done, "unexpected event %+v", event)
+}
+
+func TestFormatBytes(t *testing.T) {
+	z := zest.New(t)
+
+	for _, tc := range []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{-512, "-512 B"},
+		{-1536, "-1.5 KiB"},
+		{-5 << 20, "-5.0 MiB"},
+		{math.MinInt64, "-8.0 EiB"},
+	} {
+		got := formatBytes(tc.n)
+		z.Assert(got == tc.want, "[%d]: expected [%s], got [%s]", tc.n, tc.want, got)
+	}
+
+}
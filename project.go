@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/echo"
+)
+
+const (
+	// Project configuration file names, in order of preference when a directory
+	// holds both
+	projectFileJSON = ".vsx.json"
+	projectFileTOML = "vsx.toml"
+)
+
+const (
+	// Configuration value origins, reported by `config list --show-origin`
+	originDefault = "default"
+	originGlobal  = "global"
+	originProject = "project"
+	originEnv     = "env"
+	originFlag    = "flag"
+)
+
+var (
+	ErrProjectConfig = fmt.Errorf("failed to load project config file")
+)
+
+// LoadConfig produces the effective configuration, layering (lowest precedence
+// first):
+//
+//  1. Defaults
+//  2. The global config file
+//  3. The nearest project config file (`.vsx.json` or `vsx.toml`)
+//  4. Environment variables
+//  5. Command-line flags
+//
+// The origin of each value is recorded for `config list --show-origin`.
+func LoadConfig(cmd argv.Command) (*Config, error) {
+	cfg := &Config{origins: make(map[string]string)}
+	if err := mergeConfig(cfg, applyConfigDefaults(new(Config)), originDefault); err != nil {
+		return nil, err
+	}
+
+	// Global config file
+	global, err := ReadConfigFile()
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		echo.Debugf("No global configuration found, proceeding with defaults.")
+	case err != nil:
+		return nil, err
+	default:
+		// The global config file predates validating its values, so invalid
+		// ones are skipped with a warning rather than failing every command
+		path, _ := cfgFile(cfgFileName)
+		if err := mergeConfig(cfg, global, originGlobal+": "+path); err != nil {
+			for msg := range strings.SplitSeq(err.Error(), "\n") {
+				echo.Errorf("Ignoring %s.", msg)
+			}
+		}
+	}
+
+	// Project config file
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	if path := FindProjectConfig(wd); path != "" {
+		echo.Debugf("Using project configuration [%s].", path)
+		project, err := ReadProjectConfig(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Project files are checked into the repositories they configure,
+		// which mustn't choose the policy they're held to
+		if project.PolicyFile != "" {
+			echo.Errorf("Ignoring 'policy_file' of project configuration [%s], set globally only.", path)
+			project.PolicyFile = ""
+		}
+		if err := mergeConfig(cfg, project, originProject+": "+path); err != nil {
+			return nil, err
+		}
+		cfg.project = path
+	}
+
+	// Environment and command-line flags
+	if err := mergeConfig(cfg, LoadConfigEnv(new(Config)), originEnv); err != nil {
+		return nil, err
+	}
+	if err := mergeConfig(cfg, MergeInputs(new(Config), cmd), originFlag); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// mergeConfig layers the values set in `src` over `dst`, recording `origin` as
+// the source of each
+//
+// Gallery profiles are merged by name and routes from `src` are tried before
+// those already in `dst`. A declared extension set replaces any before it.
+func mergeConfig(dst, src *Config, origin string) error {
+	var errs []error
+	for _, key := range configKeys {
+		v := key.Get(src)
+		if v == "" {
+			continue
+		}
+		if err := key.Set(dst, v); err != nil {
+			errs = append(errs, fmt.Errorf("%w for [%s] (%s): %w", ErrConfigValue, key.Name, origin, err))
+			continue
+		}
+		dst.origins[key.Name] = origin
+	}
+
+	for _, profile := range src.Galleries {
+		i := slices.IndexFunc(dst.Galleries, func(p GalleryProfile) bool {
+			return p.Name == profile.Name
+		})
+		if i == -1 {
+			dst.Galleries = append(dst.Galleries, profile)
+		} else {
+			dst.Galleries[i] = profile
+		}
+	}
+	if len(src.Routes) > 0 {
+		dst.Routes = append(slices.Clone(src.Routes), dst.Routes...)
+	}
+	if len(src.Extensions) > 0 {
+		dst.Extensions = src.Extensions
+	}
+
+	return errors.Join(errs...)
+}
+
+// FindProjectConfig searches `dir` and each of its parents for a project
+// config file, producing the path of the nearest or the empty string if there
+// is none
+func FindProjectConfig(dir string) string {
+	for {
+		for _, name := range []string{projectFileJSON, projectFileTOML} {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ReadProjectConfig loads the project config file at `path`, JSON or TOML
+// depending on its extension
+//
+// A relative `extensions_dir` is resolved against the file's directory.
+func ReadProjectConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w [%s]: %w", ErrProjectConfig, path, err)
+	}
+
+	// TOML is decoded generically and re-encoded so both formats share the
+	// JSON field names and decoding
+	if filepath.Ext(path) == ".toml" {
+		values, err := decodeTOML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w [%s]: %w", ErrProjectConfig, path, err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return nil, fmt.Errorf("%w [%s]: %w", ErrProjectConfig, path, err)
+		}
+	}
+
+	cfg := new(Config)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%w [%s]: %w", ErrProjectConfig, path, err)
+	}
+
+	if cfg.ExtensionDir != "" && !filepath.IsAbs(cfg.ExtensionDir) {
+		cfg.ExtensionDir = filepath.Join(filepath.Dir(path), cfg.ExtensionDir)
+	}
+
+	return cfg, nil
+}
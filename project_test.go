@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/zest"
+)
+
+const testProjectTOML = `
+# Project configuration
+editor = "vscodium" # trailing comment
+os = 'linux'
+extensions = [
+  "golang.go",
+  "usernamehw.errorlens@3.26.0", # pinned
+]
+
+[[galleries]]
+name = "internal"
+host = "gallery.ourcorp.com"
+priority = -1
+
+[[routes]]
+pattern = "ourcorp.*"
+gallery = "internal"
+`
+
+func TestReadProjectConfig(t *testing.T) {
+	z := zest.New(t)
+
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	z.Assert(os.MkdirAll(sub, 0o755) == nil, "failed to create [%s]", sub)
+
+	// No project config anywhere (beneath the temp dir, at least)
+	path := filepath.Join(root, projectFileTOML)
+	z.Assert(FindProjectConfig(sub) != path, "expected no project config")
+
+	// The nearest parent's project config is found
+	err := os.WriteFile(path, []byte(testProjectTOML), 0o644)
+	z.Assert(err == nil, "failed to write project config: %s", err)
+	got := FindProjectConfig(sub)
+	z.Assert(got == path, "expected [%s], got [%s]", path, got)
+
+	project, err := ReadProjectConfig(got)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(project.Editor == "vscodium", "expected editor [vscodium], got [%s]", project.Editor)
+	z.Assert(project.OS == "linux", "expected os [linux], got [%s]", project.OS)
+	want := []string{"golang.go", "usernamehw.errorlens@3.26.0"}
+	z.Assert(slices.Equal(project.Extensions, want), "expected %q, got %q", want, project.Extensions)
+	z.Assert(len(project.Galleries) == 1 && project.Galleries[0].Priority == -1, "unexpected galleries %+v", project.Galleries)
+	z.Assert(len(project.Routes) == 1 && project.Routes[0].Gallery == "internal", "unexpected routes %+v", project.Routes)
+
+	// Later layers win and record their origin
+	cfg := &Config{origins: make(map[string]string)}
+	z.Assert(mergeConfig(cfg, &Config{Editor: "vscode", Arch: "x64"}, originGlobal) == nil, "unexpected merge error")
+	z.Assert(mergeConfig(cfg, project, originProject) == nil, "unexpected merge error")
+	z.Assert(cfg.Editor == "vscodium", "expected editor [vscodium], got [%s]", cfg.Editor)
+	z.Assert(cfg.origins["editor"] == originProject, "expected editor origin [%s], got [%s]", originProject, cfg.origins["editor"])
+	z.Assert(cfg.origins["arch"] == originGlobal, "expected arch origin [%s], got [%s]", originGlobal, cfg.origins["arch"])
+
+	// Invalid values are reported with their origin
+	err = mergeConfig(cfg, &Config{OS: "plan9"}, originEnv)
+	z.Assert(err != nil, "expected an error for an invalid os")
+}
+
+func TestLoadConfigLegacyGlobal(t *testing.T) {
+	z := zest.New(t)
+	path := testConfigDir(t)
+
+	// Written before values were validated: the host holds a scheme
+	legacy := `{"gallery_scheme": "https", "gallery_host": "https://gallery.ourcorp.com", "os": "linux"}`
+	z.Assert(os.MkdirAll(filepath.Dir(path), 0o755) == nil, "failed to create config dir")
+	z.Assert(os.WriteFile(path, []byte(legacy), 0o644) == nil, "failed to write [%s]", path)
+
+	// Invalid values are skipped, valid ones still apply
+	cmd, err := argv.Parse([]string{cmdList})
+	z.Assert(err == nil, "failed to parse: %s", err)
+	cfg, err := LoadConfig(cmd)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(cfg.GalleryHost == "", "expected gallery_host skipped, got [%s]", cfg.GalleryHost)
+	z.Assert(cfg.OS == "linux", "expected os [linux], got [%s]", cfg.OS)
+	z.Assert(cfg.origins["os"] == originGlobal+": "+path, "unexpected os origin [%s]", cfg.origins["os"])
+
+	// Invalid flags still fail
+	cmd, err = argv.Parse([]string{cmdList, "--" + flagGalleryHost, "https://gallery.ourcorp.com"})
+	z.Assert(err == nil, "failed to parse: %s", err)
+	_, err = LoadConfig(cmd)
+	z.Assert(errors.Is(err, ErrConfigValue), "expected an invalid flag value, got [%v]", err)
+}
+
+func TestLoadConfigProjectPolicy(t *testing.T) {
+	z := zest.New(t)
+	path := testConfigDir(t)
+
+	// The global policy denies everything, the project's allows everything
+	dir := t.TempDir()
+	strict := filepath.Join(dir, "strict.json")
+	z.Assert(os.WriteFile(strict, []byte(`{"default": "deny"}`), 0o644) == nil, "failed to write [%s]", strict)
+	z.Assert(SaveConfigFile(&Config{PolicyFile: strict}) == nil, "failed to write [%s]", path)
+
+	project := t.TempDir()
+	loose := filepath.Join(project, "loose.json")
+	z.Assert(os.WriteFile(loose, []byte(`{"default": "allow"}`), 0o644) == nil, "failed to write [%s]", loose)
+	projectFile := filepath.Join(project, projectFileJSON)
+	z.Assert(os.WriteFile(projectFile, []byte(`{"policy_file": "loose.json"}`), 0o644) == nil, "failed to write [%s]", projectFile)
+	t.Chdir(project)
+
+	// The project can't loosen the global policy
+	cmd, err := argv.Parse([]string{cmdList})
+	z.Assert(err == nil, "failed to parse: %s", err)
+	cfg, err := LoadConfig(cmd)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(cfg.PolicyFile == strict, "expected policy_file [%s], got [%s]", strict, cfg.PolicyFile)
+
+	policy, err := LoadPolicy(cfg)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	decision := policy.Evaluate(PolicySubject{Publisher: "someone", Name: "thing", Version: "1.0.0"})
+	z.Assert(!decision.Allowed, "expected the global policy to deny, got %+v", decision)
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	z := zest.New(t)
+
+	for _, input := range []string{
+		`editor`,
+		`editor = "vscodium`,
+		`extensions = ["a",`,
+		"editor = 1\neditor = 2",
+		`editor = vscodium`,
+		`[a.b]`,
+	} {
+		_, err := decodeTOML([]byte(input))
+		z.Assert(err != nil, "[%s]: expected an error", input)
+	}
+}
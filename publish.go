@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/echo"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	// envPublishToken is the environment variable holding the publish access
+	// token, if not provided with `--token`
+	envPublishToken = "VSX_PUBLISH_TOKEN"
+
+	// openVSXHost is the host of the Open VSX registry
+	openVSXHost = "open-vsx.org"
+)
+
+var (
+	ErrPublishManifest = fmt.Errorf("invalid extension manifest")
+	ErrPackageFile     = fmt.Errorf("invalid package file name")
+	ErrNoToken         = fmt.Errorf("no publish token, provide --token or set " + envPublishToken)
+)
+
+// extensionNamePattern matches valid extension (and publisher) names
+var extensionNamePattern = regexp.MustCompile(`(?i)^[a-z0-9][a-z0-9-]*$`)
+
+// PublishCommand validates each VSIX package in `cmd` and, unless
+// `--dry-run`, publishes it to the gallery of publishTarget
+func PublishCommand(ctx context.Context, g *Galleries, cmd argv.Command) error {
+	if len(cmd.Args) == 0 {
+		return UsageError("No VSIX packages received.")
+	}
+	_, dryRun := cmd.Flag(flagDryRun)
+
+	var target NamedGallery
+	var api gallery.PublishAPI
+	var token string
+	if !dryRun {
+		var err error
+		if target, err = publishTarget(g, cmd); err != nil {
+			return err
+		}
+		if api, err = publishAPI(target); err != nil {
+			return fmt.Errorf("gallery [%s]: %w", target.Name, err)
+		}
+		if v, ok := cmd.Flag(flagToken); ok {
+			token = v[0]
+		} else if token = os.Getenv(envPublishToken); token == "" {
+			return UsageError("%s.", ErrNoToken)
+		}
+	}
+
+	var errs []error
+	for _, file := range cmd.Args {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to open [%s]: %w", file, err))
+			continue
+		}
+		pkg, err := readPackage(file, data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := ValidatePackage(pkg); err != nil {
+			errs = append(errs, fmt.Errorf("[%s]: %w", file, err))
+			continue
+		}
+
+		id := pkg.Identity
+		if dryRun {
+			echo.Infof("Validated [%s.%s@%s], not publishing (--%s).", id.Publisher, id.Name, id.Version, flagDryRun)
+			continue
+		}
+
+		echo.Infof("Publishing [%s.%s@%s] to gallery [%s].", id.Publisher, id.Name, id.Version, target.Name)
+		if err := target.Gallery.Publish(ctx, api, token, id.Publisher, id.Name, data); err != nil {
+			errs = append(errs, fmt.Errorf("failed to publish [%s]: %w", file, err))
+			continue
+		}
+		echo.Infof("Published [%s.%s@%s].", id.Publisher, id.Name, id.Version)
+	}
+
+	return errors.Join(errs...)
+}
+
+// publishTarget produces the gallery `cmd` publishes to: that selected with
+// `--gallery`, or else the only gallery setting `publish`
+//
+// Packages are never published to a gallery merely for being the primary one,
+// which is often public.
+func publishTarget(g *Galleries, cmd argv.Command) (NamedGallery, error) {
+	if _, ok := cmd.Flag(flagGallery); ok {
+		return g.Primary()
+	}
+
+	var targets []NamedGallery
+	for _, ng := range g.galleries {
+		if ng.Publish != "" {
+			targets = append(targets, ng)
+		}
+	}
+	if len(targets) != 1 {
+		return NamedGallery{}, UsageError(
+			"Publishing requires --%s, or exactly one gallery setting its 'publish' key (found %d).",
+			flagGallery, len(targets),
+		)
+	}
+	return targets[0], nil
+}
+
+// publishAPI produces the publish API of `g`: as configured, or Open VSX's for
+// its registry and the Marketplace's otherwise
+func publishAPI(g NamedGallery) (gallery.PublishAPI, error) {
+	switch api := strings.ToLower(g.Publish); api {
+	case gallery.PublishOpenVSX, gallery.PublishMarketplace:
+		return api, nil
+	case "":
+	default:
+		return "", fmt.Errorf("%w, got [%s]", gallery.ErrPublishAPI, g.Publish)
+	}
+
+	host := g.BaseURL.Hostname()
+	if host == openVSXHost || strings.HasSuffix(host, "."+openVSXHost) {
+		return gallery.PublishOpenVSX, nil
+	}
+	return gallery.PublishMarketplace, nil
+}
+
+// ValidatePackage checks that `pkg` is publishable: its `package.json`
+// identifies it as its VSIX manifest does, with a semantic version and a
+// supported editor version range, its entrypoints are packaged and none of its
+// files escape the extension directory
+func ValidatePackage(pkg *VSIXPackage) error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrPublishManifest}, args...)...))
+	}
+	manifest, id := pkg.Manifest, pkg.Identity
+
+	// Identity
+	for _, field := range []struct{ name, value, identity string }{
+		{"publisher", manifest.Publisher, id.Publisher},
+		{"name", manifest.Name, id.Name},
+		{"version", manifest.Version, id.Version},
+	} {
+		switch {
+		case field.value == "":
+			invalid("missing %s", field.name)
+		case !strings.EqualFold(field.value, field.identity):
+			invalid("%s [%s] differs from the VSIX manifest's [%s]", field.name, field.value, field.identity)
+		}
+	}
+	if manifest.Name != "" && !extensionNamePattern.MatchString(manifest.Name) {
+		invalid("name [%s] may only hold letters, digits and dashes", manifest.Name)
+	}
+	if manifest.Version != "" {
+		if _, err := ParseSemver(manifest.Version); err != nil {
+			invalid("version: %s", err)
+		}
+	}
+
+	// Engine
+	if engine, ok := manifest.Engines["vscode"]; !ok {
+		invalid("missing engines.vscode")
+	} else if _, err := ParseConstraint(engine); err != nil {
+		invalid("engines.vscode: %s", err)
+	}
+
+	// Entrypoints
+	files := make(map[string]bool, len(pkg.Files))
+	for _, f := range pkg.Files {
+		files[f.Name] = true
+	}
+	for _, entry := range []struct{ name, file string }{
+		{"main", manifest.Main},
+		{"browser", manifest.Browser},
+	} {
+		if entry.file == "" {
+			continue
+		}
+		name := path.Join("extension", entry.file)
+		if !files[name] && !files[name+".js"] {
+			invalid("%s [%s] is not in the package", entry.name, entry.file)
+		}
+	}
+
+	// Every file must stay within the extension directory once installed
+	for _, f := range pkg.Files {
+		if !path.IsAbs(f.Name) && !strings.Contains("/"+f.Name+"/", "/../") {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%w [%s]: escapes the extension directory", ErrPackageFile, f.Name))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/zest"
+)
+
+func TestValidatePackage(t *testing.T) {
+	z := zest.New(t)
+
+	valid := testPackage(t, `{
+	  "publisher": "ourcorp", "name": "tools", "version": "1.0.0",
+	  "engines": {"vscode": "^1.80.0"},
+	  "main": "./dist/extension"
+	}`, map[string]string{"extension/dist/extension.js": ""})
+	z.Assert(ValidatePackage(valid) == nil, "unexpected error: %s", ValidatePackage(valid))
+
+	tests := []struct {
+		name        string
+		packageJSON string
+	}{
+		{"identity", `{"publisher": "ourcorp", "name": "other", "version": "1.0.0", "engines": {"vscode": "^1.80.0"}}`},
+		{"version", `{"publisher": "ourcorp", "name": "tools", "version": "1.0", "engines": {"vscode": "^1.80.0"}}`},
+		{"engine", `{"publisher": "ourcorp", "name": "tools", "version": "1.0.0"}`},
+		{"main", `{"publisher": "ourcorp", "name": "tools", "version": "1.0.0", "engines": {"vscode": "^1.80.0"}, "main": "missing.js"}`},
+	}
+	for _, test := range tests {
+		err := ValidatePackage(testPackage(t, test.packageJSON, map[string]string{}))
+		z.Assert(errors.Is(err, ErrPublishManifest), "%s: expected an invalid manifest, got [%v]", test.name, err)
+	}
+}
+
+func TestPublishTarget(t *testing.T) {
+	z := zest.New(t)
+
+	for _, tc := range []struct {
+		name      string
+		publish   [3]string
+		gallery   string
+		want      string
+		wantUsage bool
+	}{
+		{name: "the gallery setting publish", publish: [3]string{"", "openvsx", ""}, want: "internal"},
+		{name: "explicit gallery", publish: [3]string{"", "openvsx", ""}, gallery: "mirror", want: "mirror"},
+		{name: "several galleries setting publish", publish: [3]string{"", "openvsx", "marketplace"}, wantUsage: true},
+		{name: "no gallery setting publish", wantUsage: true},
+	} {
+		g, err := NewGalleries(&Config{
+			Galleries: []GalleryProfile{
+				{Name: "public", Host: "open-vsx.org", Publish: tc.publish[0]},
+				{Name: "internal", Host: "gallery.ourcorp.com", Priority: 1, Publish: tc.publish[1]},
+				{Name: "mirror", Host: "mirror.ourcorp.com", Priority: 2, Publish: tc.publish[2]},
+			},
+			Gallery: tc.gallery,
+		})
+		z.Assert(err == nil, "[%s]: unexpected error: %s", tc.name, err)
+
+		args := []string{cmdPublish, "tools-1.0.0.vsix"}
+		if tc.gallery != "" {
+			args = append(args, "--"+flagGallery, tc.gallery)
+		}
+		cmd, err := argv.Parse(args)
+		z.Assert(err == nil, "[%s]: failed to parse: %s", tc.name, err)
+
+		// The primary gallery is never assumed
+		got, err := publishTarget(g, cmd)
+		if tc.wantUsage {
+			z.Assert(err != nil && got.Name == "", "[%s]: expected a usage error, got [%s]", tc.name, got.Name)
+			continue
+		}
+		z.Assert(err == nil, "[%s]: unexpected error: %s", tc.name, err)
+		z.Assert(got.Name == tc.want, "[%s]: expected [%s], got [%s]", tc.name, tc.want, got.Name)
+	}
+}
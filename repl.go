@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/echo"
+)
+
+const (
+	replPrompt     = app + "> "
+	replMaxHistory = 1000
+)
+
+var (
+	ErrUnterminated = fmt.Errorf("unterminated quote")
+)
+
+// RunREPL starts an interactive prompt, executing each line entered as a `vsx`
+// command until `exit` (or end of input)
+//
+// Every command shares the gallery clients `g`, unless a command overrides the
+// gallery with its own flags.
+func RunREPL(ctx context.Context, g *Galleries, cfg *Config) error {
+	editor := NewLineEditor(os.Stdin, os.Stdout)
+	editor.Prompt = replPrompt
+	editor.Complete = completeREPL
+	editor.History = loadHistory(cfg.HistFilePath)
+
+	for {
+		line, err := editor.ReadLine()
+		if errors.Is(err, ErrInterrupt) {
+			continue
+		} else if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// Record history
+		if len(editor.History) == 0 || editor.History[len(editor.History)-1] != line {
+			editor.History = append(editor.History, line)
+			if err := appendHistory(cfg.HistFilePath, line); err != nil {
+				echo.Debugf("Failed to write history: %s.", err)
+			}
+		}
+
+		// Parse and exec the command
+		args, err := splitLine(line)
+		if err != nil {
+			echo.Errorf("Failed to parse input: %s.", err)
+			continue
+		}
+		cmd, err := argv.Parse(args)
+		if err != nil {
+			echo.Errorf("Failed to parse input: %s.", err)
+			continue
+		}
+
+		switch cmd.Name {
+		case cmdExit:
+			return nil
+		case cmdShell:
+			echo.Errorf("Already in a shell.")
+			continue
+		}
+
+		// Flags on the line apply to this command alone
+		lineCfg := *cfg
+		MergeInputs(&lineCfg, cmd)
+		lineGallery := g
+		if lineCfg.GalleryScheme != cfg.GalleryScheme ||
+			lineCfg.GalleryHost != cfg.GalleryHost ||
+			lineCfg.Gallery != cfg.Gallery {
+			lineGallery, err = NewGalleries(&lineCfg)
+			if err != nil {
+				echo.Errorf("ERROR: %s.", err)
+				continue
+			}
+		}
+
+		// Ctrl-C abandons the running command, returning to the prompt
+		cmdCtx, stopCmd := signal.NotifyContext(ctx, os.Interrupt)
+		err = Run(cmdCtx, lineGallery, &lineCfg, cmd)
+		stopCmd()
+		switch {
+		case errors.Is(err, context.Canceled) && ctx.Err() == nil:
+			echo.Errorf("Canceled.")
+		case err != nil:
+			echo.Errorf("ERROR: %s.", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// splitLine splits `line` into arguments on whitespace, honoring single and
+// double quotes and backslash escapes
+func splitLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+
+		case quote != '\'' && r == '\\' && i+1 < len(runes):
+			i++
+			arg.WriteRune(runes[i])
+			inArg = true
+
+		case quote != 0:
+			arg.WriteRune(r)
+
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, ErrUnterminated
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Completion
+
+var (
+	// replCommands are the commands offered for completion
+	replCommands = []string{
+		cmdAsset,
+		cmdAudit,
+		cmdChangelog,
+		cmdConfig,
+		cmdDiff,
+		cmdDownload,
+		cmdEditors,
+		cmdExit,
+		cmdInfo,
+		cmdInspect,
+		cmdInstall,
+		cmdLicenses,
+		cmdList,
+		cmdOutdated,
+		cmdPolicy,
+		cmdPublish,
+		cmdQuery,
+		cmdSBOM,
+	}
+
+	// replFlags are the flags offered for completion
+	replFlags = []string{
+		flagCategory,
+		flagChangelog,
+		flagChecksums,
+		flagDebug,
+		flagDryRun,
+		flagEditor,
+		flagEngine,
+		flagExtDir,
+		flagSBOMFormat,
+		flagFrom,
+		flagGallery,
+		flagGalleryHost,
+		flagGalleryScheme,
+		flagInstalled,
+		flagJobs,
+		flagJSON,
+		flagLimit,
+		flagLockfile,
+		flagName,
+		flagNameTemplate,
+		flagNPM,
+		flagOutput,
+		flagOutputFormat,
+		flagPageSize,
+		flagPlatform,
+		flagPortableDir,
+		flagPreRelease,
+		flagPublisher,
+		flagSave,
+		flagSHA256,
+		flagShowOrigin,
+		flagSort,
+		flagSortOrder,
+		flagTag,
+		flagTimeout,
+		flagTo,
+		flagToken,
+		flagType,
+		flagVerify,
+	}
+)
+
+// completeREPL completes commands in the first position, flags anywhere a word
+// begins with `-` and extension identifiers (from recent queries) elsewhere
+func completeREPL(line []rune, pos int) ([]string, int) {
+	start := pos
+	for start > 0 && line[start-1] != ' ' {
+		start--
+	}
+	word := string(line[start:pos])
+
+	var options []string
+	switch {
+	case strings.TrimSpace(string(line[:start])) == "":
+		options = replCommands
+
+	case strings.HasPrefix(word, "-"):
+		for _, flag := range replFlags {
+			options = append(options, "--"+flag)
+		}
+
+	default:
+		options = recentExtensions.List()
+	}
+
+	var candidates []string
+	for _, option := range options {
+		if strings.HasPrefix(option, word) {
+			candidates = append(candidates, option)
+		}
+	}
+
+	return candidates, start
+}
+
+// recentExtensions holds the identifiers of extensions seen in query (and info)
+// results during this session
+var recentExtensions = new(recentSet)
+
+type recentSet struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (self *recentSet) Add(ids ...string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for _, id := range ids {
+		if !slices.Contains(self.ids, id) {
+			self.ids = append(self.ids, id)
+		}
+	}
+}
+
+func (self *recentSet) List() []string {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	ids := slices.Clone(self.ids)
+	slices.Sort(ids)
+	return ids
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// History
+
+// loadHistory reads the most recent `replMaxHistory` lines of the history file
+// at `path`
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	history := readHistory(path)
+	if len(history) > replMaxHistory {
+		history = history[len(history)-replMaxHistory:]
+	}
+
+	return history
+}
+
+// readHistory reads every line of the history file at `path`
+func readHistory(path string) []string {
+	f, err := os.OpenFile(path, fileFlagsRead, fileModeRW)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history = append(history, line)
+		}
+	}
+	return history
+}
+
+// historySecret matches the values of flags holding secrets (ex: `--token
+// TOKEN`), quoted or not
+var historySecret = regexp.MustCompile(`(--` + flagToken + `(?:=|\s+))("[^"]*"|'[^']*'|\S+)`)
+
+// redactHistory produces `line` with the values of flags holding secrets
+// replaced, fit for the history file
+func redactHistory(line string) string {
+	return historySecret.ReplaceAllString(line, "${1}REDACTED")
+}
+
+// appendHistory appends `line` to the history file at `path`, redacting any
+// secrets
+//
+// Once the file holds `replMaxHistory` lines, it's rewritten with the most
+// recent alone.
+func appendHistory(path, line string) error {
+	if path == "" {
+		return nil
+	}
+	line = redactHistory(line)
+
+	if err := os.MkdirAll(filepath.Dir(path), fileModeRWX); err != nil {
+		return err
+	}
+
+	if history := readHistory(path); len(history) >= replMaxHistory {
+		history = append(history[len(history)-replMaxHistory+1:], line)
+		data := strings.Join(history, "\n") + "\n"
+		_, err := writeFile(context.Background(), path, strings.NewReader(data))
+		return err
+	}
+
+	f, err := os.OpenFile(path, fileFlagsAppend, fileModeRW)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
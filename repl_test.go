@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+func TestSplitLine(t *testing.T) {
+	z := zest.New(t)
+
+	testSplitLine(z, "install usernamehw.errorlens", nil, "install", "usernamehw.errorlens")
+	testSplitLine(z, "  query   python  --limit 5 ", nil, "query", "python", "--limit", "5")
+	testSplitLine(z, `query --category "Programming Languages"`, nil, "query", "--category", "Programming Languages")
+	testSplitLine(z, `query 'it''s'`, nil, "query", "its")
+	testSplitLine(z, `query it\'s`, nil, "query", "it's")
+	testSplitLine(z, `query ""`, nil, "query", "")
+	testSplitLine(z, `query "python`, ErrUnterminated)
+}
+
+func testSplitLine(z zest.Zester, input string, wantErr error, want ...string) {
+	got, err := splitLine(input)
+	if wantErr != nil {
+		z.Assert(errors.Is(err, wantErr), "expected error [%v], got [%v]", wantErr, err)
+		return
+	}
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+	z.Assert(slices.Equal(got, want), "[%s]: expected %q, got %q", input, want, got)
+}
+
+func TestAppendHistory(t *testing.T) {
+	z := zest.New(t)
+	path := filepath.Join(t.TempDir(), ".history")
+
+	for i := range replMaxHistory + 10 {
+		err := appendHistory(path, "query "+strconv.Itoa(i))
+		z.Assert(err == nil, "unexpected error: %s", err)
+	}
+
+	// The file holds the most recent lines alone
+	history := readHistory(path)
+	z.Assert(len(history) == replMaxHistory, "expected [%d] lines, got [%d]", replMaxHistory, len(history))
+	z.Assert(history[0] == "query 10", "expected the oldest line [query 10], got [%s]", history[0])
+	last := history[len(history)-1]
+	z.Assert(last == "query "+strconv.Itoa(replMaxHistory+9), "unexpected most recent line [%s]", last)
+}
+
+func TestRedactHistory(t *testing.T) {
+	z := zest.New(t)
+
+	for _, tc := range []struct{ line, want string }{
+		{"publish tools.vsix --token abc123", "publish tools.vsix --token REDACTED"},
+		{"publish tools.vsix --token  abc123 --dry-run", "publish tools.vsix --token  REDACTED --dry-run"},
+		{`publish tools.vsix --token "abc 123"`, "publish tools.vsix --token REDACTED"},
+		{"publish tools.vsix --token=abc123", "publish tools.vsix --token=REDACTED"},
+		{"query python --limit 5", "query python --limit 5"},
+	} {
+		got := redactHistory(tc.line)
+		z.Assert(got == tc.want, "[%s]: expected [%s], got [%s]", tc.line, tc.want, got)
+	}
+
+	// Tokens never reach the history file
+	path := filepath.Join(t.TempDir(), ".history")
+	z.Assert(appendHistory(path, "publish tools.vsix --token abc123") == nil, "unexpected error writing history")
+	data, _ := os.ReadFile(path)
+	z.Assert(!strings.Contains(string(data), "abc123"), "expected the token redacted, got [%s]", data)
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	// versionLatest requests the newest version, pre-releases included only
+	// with `--pre-release`
+	versionLatest = "latest"
+
+	// versionLatestStable requests the newest version that isn't a pre-release
+	versionLatestStable = "latest-stable"
+
+	// versionPreRelease requests the newest version, pre-releases included
+	versionPreRelease = "prerelease"
+)
+
+var (
+	ErrNoVersion   = fmt.Errorf("no matching version")
+	ErrVersionSpec = fmt.Errorf("invalid version specifier")
+	ErrUnsafeName  = fmt.Errorf("unsafe extension name")
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Version Specifiers
+
+// VersionSpec is a parsed extension version specifier, the part of an
+// extension input following `@`
+type VersionSpec struct {
+	raw string
+
+	// exact is set for specifiers naming a single version (ex: `3.26.0`)
+	exact string
+
+	// constraint is set for version ranges (ex: `^1.4`)
+	constraint Constraint
+
+	// preRelease reports whether pre-release versions may be selected, nil
+	// deferring to `--pre-release`
+	preRelease *bool
+}
+
+// ParseVersionSpec parses extension version specifier `s`, one of:
+//
+//	latest         the newest version (the default)
+//	latest-stable  the newest version that isn't a pre-release
+//	prerelease     the newest version, pre-releases included
+//	3.26.0         exactly the provided version
+//	^1.4 ~2.3.1    the newest version in the range (see ParseConstraint)
+//	>=1.0 <2.0
+func ParseVersionSpec(s string) (VersionSpec, error) {
+	self := VersionSpec{raw: s}
+	allow, deny := true, false
+
+	switch {
+	case s == "" || s == versionLatest:
+		self.raw = versionLatest
+	case s == versionLatestStable:
+		self.preRelease = &deny
+	case s == versionPreRelease:
+		self.preRelease = &allow
+	case strings.ContainsAny(s, "^~<>=* ") || strings.HasSuffix(s, ".x"):
+		constraint, err := ParseConstraint(s)
+		if err != nil {
+			return VersionSpec{}, fmt.Errorf("%w [%s]: %w", ErrVersionSpec, s, err)
+		}
+		self.constraint = constraint
+	default:
+		// Exact versions need only look like one, as not every extension
+		// version is a valid semantic version
+		if s[0] < '0' || s[0] > '9' {
+			return VersionSpec{}, fmt.Errorf(
+				"%w [%s]: expected a version, range, `%s`, `%s` or `%s`",
+				ErrVersionSpec, s, versionLatest, versionLatestStable, versionPreRelease,
+			)
+		}
+		self.exact = s
+	}
+
+	return self, nil
+}
+
+func (self VersionSpec) String() string {
+	return self.raw
+}
+
+// match reports whether `v` satisfies the specifier, pre-releases being
+// allowed only if `preRelease` is set
+//
+// Exact specifiers match pre-releases regardless, having asked for one by
+// name.
+func (self VersionSpec) match(v gallery.Version, preRelease bool) bool {
+	if self.exact != "" {
+		return compareVersions(v.Version, self.exact) == 0
+	}
+	if self.preRelease != nil {
+		preRelease = *self.preRelease
+	}
+
+	semver, err := ParseSemver(v.Version)
+	isPreRelease := v.PreRelease() || (err == nil && semver.Pre != "")
+	if isPreRelease && !preRelease {
+		return false
+	}
+	if self.constraint == nil {
+		return true
+	}
+	return err == nil && self.constraint.Match(semver)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Resolution
+
+// ResolveOptions constrain the versions an extension version specifier may
+// resolve to
+type ResolveOptions struct {
+	// Platform is the targeted platform (ex: `linux-x64`), versions published
+	// for other platforms are never selected
+	Platform string
+
+	// Engine is the editor version selected versions must support (ex:
+	// `1.95.0`), compatibility is not checked if empty
+	Engine string
+
+	// PreRelease allows pre-release versions to be selected by specifiers
+	// other than `prerelease` and exact versions
+	PreRelease bool
+}
+
+// ParseResolveOptions produces the version resolution options of `cfg` and
+// `cmd`
+func ParseResolveOptions(cfg *Config, cmd argv.Command) (ResolveOptions, error) {
+	_, preRelease := cmd.Flag(flagPreRelease)
+	opts := ResolveOptions{
+		Platform:   targetPlatform(cfg),
+		Engine:     cfg.Engine,
+		PreRelease: preRelease,
+	}
+	if opts.Engine != "" {
+		if _, err := ParseSemver(opts.Engine); err != nil {
+			return ResolveOptions{}, fmt.Errorf("engine: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// targetPlatform produces the target platform (ex: `linux-x64`) of `cfg`,
+// defaulting each of the OS and architecture to those of the running system
+//
+// The empty string is produced if either is unknown, in which case only
+// universal versions are selected.
+func targetPlatform(cfg *Config) string {
+	os := cfg.OS
+	if os == "" {
+		os = map[string]string{
+			"windows": "win32",
+			"linux":   "linux",
+			"darwin":  "darwin",
+		}[runtime.GOOS]
+	}
+	if os == "web" {
+		return os
+	}
+
+	arch := cfg.Arch
+	if arch == "" {
+		arch = map[string]string{
+			"amd64": "x64",
+			"arm64": "arm64",
+			"arm":   "armhf",
+			"386":   "ia32",
+		}[runtime.GOARCH]
+	}
+
+	if os == "" || arch == "" {
+		return ""
+	}
+	return os + "-" + arch
+}
+
+// ResolvedExtension is a concrete extension version, as resolved from an
+// extension input
+type ResolvedExtension struct {
+	Publisher string
+	Name      string
+	Version   string
+
+	// TargetPlatform is the platform the version was published for, or the
+	// empty string if universal
+	TargetPlatform string
+
+	// Verified reports whether the gallery verified the extension's publisher
+	Verified bool
+
+	// Gallery is the host of the gallery the version was resolved from (ex:
+	// `open-vsx.org`)
+	Gallery string
+}
+
+// ID produces the extension's `publisher.name` identifier
+func (self ResolvedExtension) ID() string {
+	return self.Publisher + "." + self.Name
+}
+
+// DirName produces the extension's install directory name, as named by the
+// editor itself (ex: `ms-python.python-2024.1.0-linux-x64`)
+//
+// The editor lowercases the `publisher.name` identifier, whatever the casing
+// of the gallery.
+func (self ResolvedExtension) DirName() string {
+	name := strings.ToLower(self.ID()) + "-" + self.Version
+	if self.TargetPlatform != "" {
+		name += "-" + self.TargetPlatform
+	}
+	return name
+}
+
+func (self ResolvedExtension) String() string {
+	s := self.ID() + "@" + self.Version
+	if self.TargetPlatform != "" {
+		s += " (" + self.TargetPlatform + ")"
+	}
+	return s
+}
+
+// ResolveExtension resolves version specifier `spec` of extension `pub`.`id`
+// against the versions published to `g`
+func ResolveExtension(
+	ctx context.Context,
+	g *Galleries,
+	pub, id, spec string,
+	opts ResolveOptions,
+) (ResolvedExtension, error) {
+	vs, err := ParseVersionSpec(spec)
+	if err != nil {
+		return ResolvedExtension{}, err
+	}
+
+	meta, from, err := g.GetExtensionVersions(ctx, pub, id)
+	if err != nil {
+		return ResolvedExtension{}, fmt.Errorf("failed to look up [%s.%s] versions: %w", pub, id, err)
+	}
+
+	version, err := selectVersion(meta.Versions, vs, opts)
+	if err != nil {
+		return ResolvedExtension{}, fmt.Errorf("[%s.%s]: %w", pub, id, err)
+	}
+
+	// Prefer the gallery's casing of names
+	ext := ResolvedExtension{
+		Publisher:      cmp.Or(meta.Publisher.Name, pub),
+		Name:           cmp.Or(meta.Name, id),
+		Version:        version.Version,
+		TargetPlatform: version.TargetPlatform,
+		Verified:       meta.Publisher.Flags.Verified || meta.Publisher.DomainVerified,
+		Gallery:        from.BaseURL.Host,
+	}
+
+	// Gallery-supplied names end up in install and cache paths
+	for _, name := range []string{ext.Publisher, ext.Name, ext.Version, ext.TargetPlatform} {
+		if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+			return ResolvedExtension{}, fmt.Errorf("[%s.%s]: %w [%s]", pub, id, ErrUnsafeName, name)
+		}
+	}
+	return ext, nil
+}
+
+// selectVersion selects the newest of `versions` matching `spec` and `opts`
+//
+// Of equal versions, those published for the targeted platform are preferred
+// over universal ones.
+func selectVersion(versions []gallery.Version, spec VersionSpec, opts ResolveOptions) (gallery.Version, error) {
+	candidates := slices.Clone(versions)
+	slices.SortStableFunc(candidates, func(a, b gallery.Version) int {
+		if c := compareVersions(b.Version, a.Version); c != 0 {
+			return c
+		}
+		// Platform-specific before universal
+		switch ua, ub := universal(a.TargetPlatform), universal(b.TargetPlatform); {
+		case ua == ub:
+			return 0
+		case ua:
+			return 1
+		default:
+			return -1
+		}
+	})
+
+	var (
+		skipped    []string
+		preRelease bool
+	)
+	for _, v := range candidates {
+		switch {
+		case !universal(v.TargetPlatform) && v.TargetPlatform != opts.Platform:
+			continue
+		case !spec.match(v, opts.PreRelease):
+			preRelease = preRelease || spec.match(v, true)
+			continue
+		}
+
+		if !engineSupports(v.Engine(), opts.Engine) {
+			skipped = append(skipped, fmt.Sprintf("%s (requires engine %s)", v.Version, v.Engine()))
+			continue
+		}
+
+		if universal(v.TargetPlatform) {
+			v.TargetPlatform = ""
+		}
+		return v, nil
+	}
+
+	err := fmt.Errorf("%w for [%s]", ErrNoVersion, spec)
+	if opts.Platform != "" {
+		err = fmt.Errorf("%w on platform [%s]", err, opts.Platform)
+	}
+	if preRelease {
+		err = fmt.Errorf("%w (pre-releases match, allow them with --%s)", err, flagPreRelease)
+	}
+	if len(skipped) > 0 {
+		err = fmt.Errorf(
+			"%w, skipped versions incompatible with engine [%s]: %s",
+			err, opts.Engine, strings.Join(skipped, ", "),
+		)
+	}
+	return gallery.Version{}, err
+}
+
+// universal reports whether `platform` is the universal target platform
+func universal(platform string) bool {
+	return platform == "" || platform == platformUniversal
+}
+
+// engineSupports reports whether editor version `engine` satisfies version
+// range `requires`
+//
+// Unchecked (and unparseable) ranges are considered satisfied, so as not to
+// block installs over metadata we can't interpret.
+func engineSupports(requires, engine string) bool {
+	if engine == "" || requires == "" {
+		return true
+	}
+	constraint, err := ParseConstraint(requires)
+	if err != nil {
+		return true
+	}
+	v, err := ParseSemver(engine)
+	if err != nil {
+		return true
+	}
+	// Editor pre-releases (ex: `1.96.0-insider`) support what their release does
+	v.Pre = ""
+	return constraint.Match(v)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/illbjorn/vsx/gallery"
+	"github.com/illbjorn/zest"
+)
+
+func TestSelectVersion(t *testing.T) {
+	z := zest.New(t)
+
+	var versions []gallery.Version
+	err := json.Unmarshal([]byte(`[
+	  {"version": "2.1.0", "properties": [{"key": "Microsoft.VisualStudio.Code.PreRelease", "value": "true"}]},
+	  {"version": "2.0.0", "properties": [{"key": "Microsoft.VisualStudio.Code.Engine", "value": "^1.90.0"}]},
+	  {"version": "1.5.0"},
+	  {"version": "1.5.0", "targetPlatform": "linux-x64"},
+	  {"version": "1.4.0", "targetPlatform": "darwin-arm64"}
+	]`), &versions)
+	z.Assert(err == nil, "failed to decode versions: %s", err)
+
+	linux := ResolveOptions{Platform: "linux-x64"}
+	testSelectVersion(z, versions, "", linux, "2.0.0", "", nil)
+	testSelectVersion(z, versions, "latest", ResolveOptions{Platform: "linux-x64", PreRelease: true}, "2.1.0", "", nil)
+
+	// Incompatible engines are skipped, platform-specific versions preferred
+	old := ResolveOptions{Platform: "linux-x64", Engine: "1.85.0"}
+	testSelectVersion(z, versions, "", old, "1.5.0", "linux-x64", nil)
+	testSelectVersion(z, versions, "", ResolveOptions{Engine: "1.95.0-insider"}, "2.0.0", "", nil)
+
+	// Exact versions
+	testSelectVersion(z, versions, "1.5.0", ResolveOptions{}, "1.5.0", "", nil)
+	testSelectVersion(z, versions, "1.4.0", ResolveOptions{Platform: "darwin-arm64"}, "1.4.0", "darwin-arm64", nil)
+	testSelectVersion(z, versions, "1.4.0", linux, "", "", ErrNoVersion)
+	testSelectVersion(z, versions, "2.0.0", old, "", "", ErrNoVersion)
+
+	// Ranges and channels
+	testSelectVersion(z, versions, "^1.4", linux, "1.5.0", "linux-x64", nil)
+	testSelectVersion(z, versions, "~1.4.0", ResolveOptions{}, "", "", ErrNoVersion)
+	testSelectVersion(z, versions, ">=1.0 <2.0", ResolveOptions{}, "1.5.0", "", nil)
+	testSelectVersion(z, versions, "^2.1", linux, "", "", ErrNoVersion)
+	testSelectVersion(z, versions, "^2.1", ResolveOptions{PreRelease: true}, "2.1.0", "", nil)
+	testSelectVersion(z, versions, "prerelease", linux, "2.1.0", "", nil)
+	testSelectVersion(z, versions, "latest-stable", ResolveOptions{PreRelease: true}, "2.0.0", "", nil)
+
+	// Releases follow their pre-releases
+	var releases []gallery.Version
+	err = json.Unmarshal([]byte(`[{"version": "1.0.0-beta"}, {"version": "1.0.0"}, {"version": "1.0.0-alpha"}]`), &releases)
+	z.Assert(err == nil, "failed to decode versions: %s", err)
+	testSelectVersion(z, releases, "", ResolveOptions{}, "1.0.0", "", nil)
+}
+
+func testSelectVersion(
+	z zest.Zester,
+	versions []gallery.Version,
+	spec string,
+	opts ResolveOptions,
+	wantVer, wantPlatform string,
+	wantErr error,
+) {
+	vs, err := ParseVersionSpec(spec)
+	z.Assert(err == nil, "[%s]: expected no error, got [%s]", spec, err)
+
+	got, err := selectVersion(versions, vs, opts)
+	if wantErr != nil {
+		z.Assert(errors.Is(err, wantErr), "[%s]: expected error [%v], got [%v]", spec, wantErr, err)
+		return
+	}
+	z.Assert(err == nil, "[%s]: expected no error, got [%s]", spec, err)
+	z.Assert(got.Version == wantVer, "[%s]: expected version [%s], got [%s]", spec, wantVer, got.Version)
+	z.Assert(got.TargetPlatform == wantPlatform, "[%s]: expected platform [%s], got [%s]", spec, wantPlatform, got.TargetPlatform)
+}
+
+func TestConstraint(t *testing.T) {
+	z := zest.New(t)
+
+	for _, tc := range []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"^1.4", "1.9.2", true},
+		{"^1.4", "2.0.0", false},
+		{"^1.4", "1.3.9", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"~2.3.1", "2.3.7", true},
+		{"~2.3.1", "2.4.0", false},
+		{">=1.0 <2.0", "1.99.0", true},
+		{">=1.0 <2.0", "2.0.0", false},
+		{"1.4", "1.4.7", true},
+		{"1.4.2", "1.4.3", false},
+		{"*", "0.0.1", true},
+		{">=1.0.0", "1.0.0-beta.2", false},
+	} {
+		c, err := ParseConstraint(tc.constraint)
+		z.Assert(err == nil, "[%s]: expected no error, got [%s]", tc.constraint, err)
+		v, err := ParseSemver(tc.version)
+		z.Assert(err == nil, "[%s]: expected no error, got [%s]", tc.version, err)
+		z.Assert(c.Match(v) == tc.want, "[%s] matching [%s]: expected [%t]", tc.constraint, tc.version, tc.want)
+	}
+
+	_, err := ParseConstraint(">=one")
+	z.Assert(errors.Is(err, ErrConstraint), "expected [%v], got [%v]", ErrConstraint, err)
+}
+
+func TestCompareVersions(t *testing.T) {
+	z := zest.New(t)
+
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0-beta", 1},
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.10", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"1.2", "1.2.0", 0},
+		{"2024.1.0", "2024.1.0", 0},
+		{"1.0.0.1", "1.0.0", 1},
+	} {
+		got := compareVersions(tc.a, tc.b)
+		z.Assert(got == tc.want, "[%s] vs [%s]: expected [%d], got [%d]", tc.a, tc.b, tc.want, got)
+	}
+}
+
+func TestDirName(t *testing.T) {
+	z := zest.New(t)
+
+	for _, tc := range []struct {
+		ext  ResolvedExtension
+		want string
+	}{
+		{ResolvedExtension{Publisher: "ms-python", Name: "python", Version: "2024.1.0"}, "ms-python.python-2024.1.0"},
+		{ResolvedExtension{Publisher: "GitHub", Name: "Copilot", Version: "1.2.3"}, "github.copilot-1.2.3"},
+		{ResolvedExtension{Publisher: "Ourcorp", Name: "Tools", Version: "1.0.0-RC.1", TargetPlatform: "linux-x64"}, "ourcorp.tools-1.0.0-RC.1-linux-x64"},
+	} {
+		got := tc.ext.DirName()
+		z.Assert(got == tc.want, "[%s]: expected [%s], got [%s]", tc.ext, tc.want, got)
+	}
+}
+
+func TestResolveExtensionUnsafe(t *testing.T) {
+	z := zest.New(t)
+
+	var (
+		publisher = "ourcorp"
+		version   = "1.0.0"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w,
+			`{"results": [{"extensions": [{"publisher": {"publisherName": %q}, "extensionName": "tools", "versions": [{"version": %q}]}]}]}`,
+			publisher, version,
+		)
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	g, err := NewGalleries(&Config{GalleryScheme: u.Scheme, GalleryHost: u.Host})
+	z.Assert(err == nil, "unexpected error: %s", err)
+
+	ext, err := ResolveExtension(context.Background(), g, "ourcorp", "tools", "", ResolveOptions{})
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(ext.DirName() == "ourcorp.tools-1.0.0", "unexpected dir name [%s]", ext.DirName())
+
+	// Gallery names escaping the extension directory are rejected
+	for _, test := range []struct{ publisher, version string }{
+		{"ourcorp", "1.0.0/../../../home/u"},
+		{"ourcorp", `1.0.0\..\x`},
+		{"..", "1.0.0"},
+		{"../ourcorp", "1.0.0"},
+	} {
+		publisher, version = test.publisher, test.version
+		_, err := ResolveExtension(context.Background(), g, "ourcorp", "tools", "", ResolveOptions{})
+		z.Assert(errors.Is(err, ErrUnsafeName), "[%s@%s]: expected [%s], got [%v]", test.publisher, test.version, ErrUnsafeName, err)
+	}
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/illbjorn/argv"
+)
+
+// SBOM formats
+const (
+	sbomCycloneDX = "cyclonedx"
+	sbomSPDX      = "spdx"
+)
+
+const (
+	// cycloneDXVersion is the CycloneDX specification version produced
+	cycloneDXVersion = "1.5"
+
+	// spdxVersion is the SPDX specification version produced
+	spdxVersion = "SPDX-2.3"
+
+	// spdxNoAssertion marks SPDX fields left undetermined
+	spdxNoAssertion = "NOASSERTION"
+
+	// purlTypeExtension is the package URL type of editor extensions
+	purlTypeExtension = "vscode-extension"
+)
+
+var (
+	ErrSBOMFormat = fmt.Errorf("invalid SBOM format, expected 'cyclonedx' or 'spdx'")
+)
+
+// SBOMComponent is a single component of a software bill of materials: an
+// extension or an npm package bundled by one
+type SBOMComponent struct {
+	// Group is the extension's publisher or the npm package's scope (ex:
+	// `@types`), if any
+	Group   string
+	Name    string
+	Version string
+	PURL    string
+
+	// SHA256 is the hex-encoded SHA-256 digest of the extension's package, or
+	// the empty string if unknown
+	SHA256 string
+
+	// License is the component's SPDX license expression, or the empty string
+	// if unidentified, and Declared its license as declared
+	License  string
+	Declared string
+
+	// Components are the npm packages bundled by an extension
+	Components []SBOMComponent
+}
+
+// SBOMCommand writes a software bill of materials of each extension (or
+// `.vsix` file) in `cmd`, of the project's declared extensions with
+// `--lockfile` and of every installed extension with `--installed` (the
+// default given nothing else) to `--output`, or stdout
+func SBOMCommand(ctx context.Context, g *Galleries, cfg *Config, cmd argv.Command) error {
+	format, err := ParseSBOMFormat(cmd)
+	if err != nil {
+		return UsageError("%s.", err)
+	}
+	inputs, installed, err := packageInputs(cfg, cmd)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		installed = true
+	}
+	_, npm := cmd.Flag(flagNPM)
+
+	pkgs, errs := loadPackages(ctx, g, cfg, cmd, inputs, installed)
+	cache := NewPackageCache(cfg)
+	components := make([]SBOMComponent, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		component, err := extensionComponent(pkg, g, cache, npm)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		components = append(components, component)
+	}
+
+	var doc any
+	switch serial, now := newUUID(), time.Now().UTC(); format {
+	case sbomSPDX:
+		doc = spdxDocument(components, serial, now)
+	default:
+		doc = cycloneDXDocument(components, serial, now)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if v, ok := cmd.Flag(flagOutput, flagOutputShort); ok {
+		_, err = writeFile(ctx, v[0], bytes.NewReader(data))
+	} else {
+		_, err = os.Stdout.Write(data)
+	}
+	return errors.Join(err, errors.Join(errs...))
+}
+
+// ParseSBOMFormat produces the SBOM format requested with `--format`,
+// defaulting to CycloneDX
+func ParseSBOMFormat(cmd argv.Command) (string, error) {
+	v, ok := cmd.Flag(flagSBOMFormat)
+	if !ok {
+		return sbomCycloneDX, nil
+	}
+	switch format := strings.ToLower(v[0]); format {
+	case sbomCycloneDX, sbomSPDX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w, got [%s]", ErrSBOMFormat, v[0])
+	}
+}
+
+// extensionComponent produces the SBOM component of `pkg`, including its
+// bundled npm packages if `npm` is set
+//
+// Installed extensions have no package to digest, unless `cache` holds it for
+// the first of the galleries in `g` the extension resolves from to do so.
+func extensionComponent(pkg *VSIXPackage, g *Galleries, cache PackageCache, npm bool) (SBOMComponent, error) {
+	license := PackageLicense(pkg)
+	id := pkg.Identity
+	self := SBOMComponent{
+		Group:    cmp.Or(id.Publisher, pkg.Manifest.Publisher),
+		Name:     cmp.Or(id.Name, pkg.Manifest.Name),
+		Version:  cmp.Or(id.Version, pkg.Manifest.Version),
+		SHA256:   pkg.SHA256,
+		License:  license.License,
+		Declared: license.Declared,
+	}
+	self.PURL = extensionPURL(self.Group, self.Name, self.Version, id.TargetPlatform)
+
+	if self.SHA256 == "" {
+		ext := ResolvedExtension{Publisher: self.Group, Name: self.Name, Version: self.Version, TargetPlatform: id.TargetPlatform}
+		for _, from := range g.For(self.Group, self.Name) {
+			ext.Gallery = from.BaseURL.Host
+			data, ok := cache.Get(ext)
+			if !ok {
+				continue
+			}
+			digests, err := digest(bytes.NewReader(data))
+			if err != nil {
+				return SBOMComponent{}, err
+			}
+			self.SHA256 = digests.SHA256
+			break
+		}
+	}
+
+	if npm && pkg.extension != nil {
+		var err error
+		if self.Components, err = npmComponents(pkg.extension); err != nil {
+			return SBOMComponent{}, fmt.Errorf("failed to read the npm packages of [%s.%s]: %w", self.Group, self.Name, err)
+		}
+	}
+
+	return self, nil
+}
+
+// npmManifest is the subset of an npm package's `package.json` describing it
+type npmManifest struct {
+	Name    string     `json:"name"`
+	Version string     `json:"version"`
+	License npmLicense `json:"license"`
+}
+
+// npmLicense is an npm license field, written either as an SPDX expression or
+// (deprecated) as an object naming its type
+type npmLicense string
+
+func (self *npmLicense) UnmarshalJSON(data []byte) error {
+	var license struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, (*string)(self)); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, &license); err != nil {
+		return err
+	}
+	*self = npmLicense(license.Type)
+	return nil
+}
+
+// npmComponents produces the npm packages beneath `node_modules` in `fsys`,
+// nested dependencies included, once per name and version
+func npmComponents(fsys fs.FS) ([]SBOMComponent, error) {
+	var components []SBOMComponent
+	seen := make(map[string]bool)
+
+	err := fs.WalkDir(fsys, "node_modules", func(file string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && file == "node_modules" {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() || path.Base(file) != "package.json" || !isNPMPackageDir(path.Dir(file)) {
+			return err
+		}
+
+		f, err := fsys.Open(file)
+		if err != nil {
+			return err
+		}
+		var manifest npmManifest
+		err = json.NewDecoder(f).Decode(&manifest)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode [%s]: %w", file, err)
+		}
+
+		key := manifest.Name + "@" + manifest.Version
+		if manifest.Name == "" || manifest.Version == "" || seen[key] {
+			return nil
+		}
+		seen[key] = true
+
+		component := SBOMComponent{
+			Name:     manifest.Name,
+			Version:  manifest.Version,
+			PURL:     npmPURL(manifest.Name, manifest.Version),
+			Declared: string(manifest.License),
+		}
+		if scope, name, ok := strings.Cut(manifest.Name, "/"); ok {
+			component.Group, component.Name = scope, name
+		}
+		if expr, category := parseLicenseExpression(component.Declared); category != licenseUnknown {
+			component.License = expr
+		}
+		components = append(components, component)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// By full package name (ex: `@types/node`)
+	slices.SortFunc(components, func(a, b SBOMComponent) int {
+		return cmp.Or(
+			strings.Compare(path.Join(a.Group, a.Name), path.Join(b.Group, b.Name)),
+			compareVersions(a.Version, b.Version),
+		)
+	})
+	return components, nil
+}
+
+// isNPMPackageDir reports whether `dir` is a package directory within
+// `node_modules` (ex: `node_modules/semver`, `node_modules/@types/node`)
+func isNPMPackageDir(dir string) bool {
+	parent := path.Dir(dir)
+	if path.Base(parent) == "node_modules" {
+		return true
+	}
+	return strings.HasPrefix(path.Base(parent), "@") && path.Base(path.Dir(parent)) == "node_modules"
+}
+
+// extensionPURL produces the package URL of an extension version (ex:
+// `pkg:vscode-extension/usernamehw/errorlens@3.26.0`), qualified by its target
+// platform if platform specific
+func extensionPURL(publisher, name, version, platform string) string {
+	purl := "pkg:" + purlTypeExtension + "/" + url.PathEscape(publisher) + "/" + url.PathEscape(name) + "@" + url.PathEscape(version)
+	if platform != "" && platform != platformUniversal {
+		purl += "?platform=" + url.QueryEscape(platform)
+	}
+	return purl
+}
+
+// npmPURL produces the package URL of an npm package version (ex:
+// `pkg:npm/%40types/node@20.1.0`)
+func npmPURL(name, version string) string {
+	var escaped []string
+	for _, segment := range strings.Split(name, "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	return "pkg:npm/" + strings.ReplaceAll(strings.Join(escaped, "/"), "@", "%40") + "@" + url.PathEscape(version)
+}
+
+// newUUID produces a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CycloneDX
+
+type cdxBOM struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cdxComponent `json:"components"`
+	} `json:"tools"`
+}
+
+type cdxComponent struct {
+	Type       string         `json:"type"`
+	BOMRef     string         `json:"bom-ref,omitempty"`
+	Group      string         `json:"group,omitempty"`
+	Name       string         `json:"name"`
+	Version    string         `json:"version,omitempty"`
+	PURL       string         `json:"purl,omitempty"`
+	Hashes     []cdxHash      `json:"hashes,omitempty"`
+	Licenses   []cdxLicense   `json:"licenses,omitempty"`
+	Components []cdxComponent `json:"components,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	License    *cdxLicenseID `json:"license,omitempty"`
+	Expression string        `json:"expression,omitempty"`
+}
+
+type cdxLicenseID struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// cycloneDXDocument produces the CycloneDX BOM of `components`
+func cycloneDXDocument(components []SBOMComponent, serial string, now time.Time) cdxBOM {
+	self := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXVersion,
+		SerialNumber: "urn:uuid:" + serial,
+		Version:      1,
+		Components:   make([]cdxComponent, 0, len(components)),
+	}
+	self.Metadata.Timestamp = now.Format(time.RFC3339)
+	self.Metadata.Tools.Components = []cdxComponent{{Type: "application", Name: app}}
+
+	seen := make(map[string]bool)
+	for _, component := range components {
+		c := cycloneDXComponent(component, "application")
+		setBOMRefs(&c, "", seen)
+		self.Components = append(self.Components, c)
+	}
+	return self
+}
+
+// setBOMRefs sets the `bom-ref` of `c` and its nested components, unique
+// among those `seen`
+//
+// Nested refs are built from their parent's (ex: an npm package bundled by two
+// extensions), and repeats numbered (ex: an extension installed in two
+// editors, `pkg:vscode-extension/ourcorp/tools@1.0.0#2`).
+func setBOMRefs(c *cdxComponent, parent string, seen map[string]bool) {
+	base := c.PURL
+	if parent != "" {
+		base = parent + "|" + c.PURL
+	}
+	ref := base
+	for n := 2; seen[ref]; n++ {
+		ref = base + "#" + strconv.Itoa(n)
+	}
+	seen[ref] = true
+	c.BOMRef = ref
+
+	for i := range c.Components {
+		setBOMRefs(&c.Components[i], ref, seen)
+	}
+}
+
+// cycloneDXComponent produces the CycloneDX component of `component`, of type
+// `typ`, its npm packages being libraries
+func cycloneDXComponent(component SBOMComponent, typ string) cdxComponent {
+	self := cdxComponent{
+		Type:    typ,
+		Group:   component.Group,
+		Name:    component.Name,
+		Version: component.Version,
+		PURL:    component.PURL,
+	}
+	if component.SHA256 != "" {
+		self.Hashes = []cdxHash{{Alg: "SHA-256", Content: component.SHA256}}
+	}
+
+	// Known SPDX licenses are written by ID, any other expression (compound or
+	// `+` forms, ex: `Apache-2.0+`) as an expression and unidentified licenses
+	// by name
+	_, known := spdxLicenses[strings.ToLower(component.License)]
+	switch {
+	case component.License != "" && known:
+		self.Licenses = []cdxLicense{{License: &cdxLicenseID{ID: component.License}}}
+	case component.License != "":
+		self.Licenses = []cdxLicense{{Expression: component.License}}
+	case component.Declared != "":
+		self.Licenses = []cdxLicense{{License: &cdxLicenseID{Name: component.Declared}}}
+	}
+
+	for _, nested := range component.Components {
+		self.Components = append(self.Components, cycloneDXComponent(nested, "library"))
+	}
+	return self
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SPDX
+
+type spdxDoc struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo"`
+	Supplier         string            `json:"supplier,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	Checksums        []spdxChecksum    `json:"checksums,omitempty"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxDocument produces the SPDX document of `components`, describing each
+// extension and recording that it contains its npm packages
+func spdxDocument(components []SBOMComponent, serial string, now time.Time) spdxDoc {
+	self := spdxDoc{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              app + "-sbom",
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + app + "-" + serial,
+		CreationInfo: spdxCreationInfo{
+			Created:  now.Format(time.RFC3339),
+			Creators: []string{"Tool: " + app},
+		},
+		Packages:      []spdxPackage{},
+		Relationships: []spdxRelationship{},
+	}
+
+	for i, component := range components {
+		id := "SPDXRef-Extension-" + strconv.Itoa(i+1)
+		self.Packages = append(self.Packages, spdxPackageOf(component, id))
+		self.Relationships = append(self.Relationships, spdxRelationship{self.SPDXID, "DESCRIBES", id})
+
+		for j, nested := range component.Components {
+			nestedID := id + "-npm-" + strconv.Itoa(j+1)
+			self.Packages = append(self.Packages, spdxPackageOf(nested, nestedID))
+			self.Relationships = append(self.Relationships, spdxRelationship{id, "CONTAINS", nestedID})
+		}
+	}
+	return self
+}
+
+// spdxPackageOf produces the SPDX package of `component`, identified by `id`
+func spdxPackageOf(component SBOMComponent, id string) spdxPackage {
+	// npm scopes are part of the package name, extension publishers supply
+	// the extension
+	name, supplier := component.Name, ""
+	switch {
+	case strings.HasPrefix(component.Group, "@"):
+		name = component.Group + "/" + component.Name
+	case component.Group != "":
+		name = component.Group + "." + component.Name
+		supplier = "Organization: " + component.Group
+	}
+
+	self := spdxPackage{
+		Name:             name,
+		SPDXID:           id,
+		VersionInfo:      component.Version,
+		Supplier:         supplier,
+		DownloadLocation: spdxNoAssertion,
+		LicenseConcluded: spdxNoAssertion,
+		LicenseDeclared:  cmp.Or(component.License, spdxNoAssertion),
+		CopyrightText:    spdxNoAssertion,
+		ExternalRefs: []spdxExternalRef{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  component.PURL,
+		}},
+	}
+	if component.SHA256 != "" {
+		self.Checksums = []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: component.SHA256}}
+	}
+	return self
+}
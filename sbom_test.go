@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/illbjorn/zest"
+)
+
+func TestExtensionComponent(t *testing.T) {
+	z := zest.New(t)
+
+	pkg := testPackage(t, `{"license": "MIT"}`, map[string]string{
+		"extension/node_modules/semver/package.json":                      `{"name": "semver", "version": "7.6.0", "license": "ISC"}`,
+		"extension/node_modules/semver/lib/package.json":                  `{"name": "internal", "version": "0.0.0"}`,
+		"extension/node_modules/@types/node/package.json":                 `{"name": "@types/node", "version": "20.1.0", "license": {"type": "MIT"}}`,
+		"extension/node_modules/glob/package.json":                        `{"name": "glob", "version": "10.0.0", "license": "Custom"}`,
+		"extension/node_modules/glob/node_modules/semver/package.json":    `{"name": "semver", "version": "6.3.1", "license": "ISC"}`,
+		"extension/node_modules/glob/node_modules/minimatch/package.json": `{"name": "minimatch", "version": "9.0.0", "license": "ISC"}`,
+		"extension/node_modules/lodash/node_modules/semver/package.json":  `{"name": "semver", "version": "6.3.1", "license": "ISC"}`,
+	})
+	pkg.SHA256 = strings.Repeat("ab", 32)
+
+	g := testPackageGallery(t)
+	ext, err := extensionComponent(pkg, g, PackageCache{}, true)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(ext.PURL == "pkg:vscode-extension/ourcorp/tools@1.0.0?platform=linux-x64", "unexpected purl [%s]", ext.PURL)
+	z.Assert(ext.License == "MIT", "expected license [MIT], got [%s]", ext.License)
+
+	var got []string
+	for _, c := range ext.Components {
+		got = append(got, c.PURL+" "+c.License)
+	}
+	want := []string{
+		"pkg:npm/%40types/node@20.1.0 MIT",
+		"pkg:npm/glob@10.0.0 ",
+		"pkg:npm/minimatch@9.0.0 ISC",
+		"pkg:npm/semver@6.3.1 ISC",
+		"pkg:npm/semver@7.6.0 ISC",
+	}
+	z.Assert(slices.Equal(got, want), "expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+
+	// Documents
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	cdx := cycloneDXDocument([]SBOMComponent{ext}, "serial", now)
+	z.Assert(len(cdx.Components) == 1, "expected one component, got %d", len(cdx.Components))
+	z.Assert(len(cdx.Components[0].Components) == 5, "expected 5 nested components, got %d", len(cdx.Components[0].Components))
+	z.Assert(cdx.Components[0].Hashes[0].Content == pkg.SHA256, "unexpected hash [%s]", cdx.Components[0].Hashes[0].Content)
+	z.Assert(cdx.Components[0].Components[1].Licenses[0].License.Name == "Custom", "expected unidentified license by name")
+
+	// The same extension installed twice, bundling the same npm packages,
+	// still has unique refs
+	cdx = cycloneDXDocument([]SBOMComponent{ext, ext}, "serial", now)
+	refs := make(map[string]bool)
+	for _, c := range cdx.Components {
+		for _, ref := range append([]cdxComponent{c}, c.Components...) {
+			z.Assert(ref.BOMRef != "" && !refs[ref.BOMRef], "expected a unique bom-ref, got [%s]", ref.BOMRef)
+			refs[ref.BOMRef] = true
+		}
+	}
+	z.Assert(cdx.Components[0].BOMRef == ext.PURL, "expected the first ref to be the purl, got [%s]", cdx.Components[0].BOMRef)
+	z.Assert(cdx.Components[1].BOMRef == ext.PURL+"#2", "expected a numbered repeat, got [%s]", cdx.Components[1].BOMRef)
+
+	// Only known SPDX identifiers are written by ID, `+` forms and compound
+	// licenses as expressions
+	for _, tc := range []struct {
+		license, wantID, wantExpression string
+	}{
+		{"MIT", "MIT", ""},
+		{"Apache-2.0+", "", "Apache-2.0+"},
+		{"GPL-2.0-or-later WITH Classpath-exception-2.0", "", "GPL-2.0-or-later WITH Classpath-exception-2.0"},
+		{"(MIT OR Apache-2.0)", "", "(MIT OR Apache-2.0)"},
+	} {
+		licenses := cycloneDXComponent(SBOMComponent{Name: "tools", License: tc.license}, "library").Licenses
+		z.Assert(len(licenses) == 1, "[%s]: expected one license, got %d", tc.license, len(licenses))
+		var gotID string
+		if licenses[0].License != nil {
+			gotID = licenses[0].License.ID
+		}
+		z.Assert(gotID == tc.wantID, "[%s]: expected ID [%s], got [%s]", tc.license, tc.wantID, gotID)
+		z.Assert(licenses[0].Expression == tc.wantExpression, "[%s]: expected expression [%s], got [%s]", tc.license, tc.wantExpression, licenses[0].Expression)
+	}
+
+	spdx := spdxDocument([]SBOMComponent{ext}, "serial", now)
+	z.Assert(len(spdx.Packages) == 6, "expected 6 packages, got %d", len(spdx.Packages))
+	z.Assert(spdx.Packages[0].Name == "ourcorp.tools", "unexpected package name [%s]", spdx.Packages[0].Name)
+	z.Assert(spdx.Packages[1].Name == "@types/node", "unexpected package name [%s]", spdx.Packages[1].Name)
+	z.Assert(spdx.Packages[2].LicenseDeclared == spdxNoAssertion, "expected an unasserted license, got [%s]", spdx.Packages[2].LicenseDeclared)
+	z.Assert(len(spdx.Relationships) == 6, "expected 6 relationships, got %d", len(spdx.Relationships))
+}
+
+func TestExtensionComponentCachedHash(t *testing.T) {
+	z := zest.New(t)
+	g := testPackageGallery(t)
+	primary, _ := g.Primary()
+
+	// Installed extensions have no package of their own to digest
+	installed := testPackage(t, `{"license": "MIT"}`, map[string]string{})
+	ext := ResolvedExtension{Publisher: "ourcorp", Name: "tools", Version: "1.0.0", TargetPlatform: "linux-x64"}
+	data := []byte("package")
+	digests, _ := digest(bytes.NewReader(data))
+
+	// A package cached for another gallery is not the installed one
+	cache := NewPackageCache(&Config{CacheDir: t.TempDir()})
+	ext.Gallery = "gallery.ourcorp.com"
+	z.Assert(cache.Put(t.Context(), ext, data) == nil, "failed to cache package")
+	component, err := extensionComponent(installed, g, cache, false)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(component.SHA256 == "", "expected no hash, got [%s]", component.SHA256)
+
+	// That of the gallery the extension resolves from is
+	ext.Gallery = primary.BaseURL.Host
+	z.Assert(cache.Put(t.Context(), ext, data) == nil, "failed to cache package")
+	component, err = extensionComponent(installed, g, cache, false)
+	z.Assert(err == nil, "unexpected error: %s", err)
+	z.Assert(component.SHA256 == digests.SHA256, "expected hash [%s], got [%s]", digests.SHA256, component.SHA256)
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"io"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	colPadding     = "  "
+	colMinWidth    = 8
+	truncateMarker = "..."
+
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+)
+
+// Table is a simple column-aligned table
+//
+// Column widths are measured in terminal cells rather than bytes or runes, so
+// wide (CJK, emoji) and zero-width (combining marks) characters align
+// correctly.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+
+	// MaxWidth bounds the total width of each rendered line, shrinking the
+	// widest columns (and truncating their values) to fit
+	//
+	// If MaxWidth is zero, columns are never shrunk.
+	MaxWidth int
+
+	// Decorate enables a bold header and a box-drawn header separator
+	Decorate bool
+}
+
+// renderTable writes `rows` to `w` beneath `columns` headers
+//
+// If `w` is a terminal, the table is fit to the terminal's width and decorated.
+// Otherwise it's written plain, at its natural width.
+func renderTable(w io.Writer, columns []string, rows [][]string) error {
+	table := Table{Columns: columns, Rows: rows}
+
+	if f, ok := w.(*os.File); ok && isTerminal(f) {
+		table.MaxWidth = terminalWidth(f)
+		table.Decorate = os.Getenv("NO_COLOR") == ""
+	}
+
+	return table.Render(w)
+}
+
+func (self Table) Render(w io.Writer) error {
+	widths := self.widths()
+
+	var b strings.Builder
+
+	// Header
+	if self.Decorate {
+		b.WriteString(ansiBold)
+	}
+	writeTableRow(&b, widths, self.Columns)
+	if self.Decorate {
+		// Reset before the newline so a resized terminal doesn't smear the
+		// attribute
+		line := strings.TrimSuffix(b.String(), "\n")
+		b.Reset()
+		b.WriteString(line + ansiReset + "\n")
+
+		for i, width := range widths {
+			if i > 0 {
+				b.WriteString(colPadding)
+			}
+			b.WriteString(strings.Repeat("─", width))
+		}
+		b.WriteString("\n")
+	}
+
+	// Rows
+	for _, row := range self.Rows {
+		writeTableRow(&b, widths, row)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// widths produces the display width of each column, shrunk to fit MaxWidth if
+// necessary
+func (self Table) widths() []int {
+	widths := make([]int, len(self.Columns))
+	for _, row := range append([][]string{self.Columns}, self.Rows...) {
+		for i := range widths {
+			if i < len(row) {
+				widths[i] = max(widths[i], displayWidth(row[i]))
+			}
+		}
+	}
+
+	if self.MaxWidth <= 0 {
+		return widths
+	}
+
+	// Shrink the widest column one cell at a time until we fit (or every column
+	// has hit its minimum)
+	available := self.MaxWidth - len(colPadding)*(len(widths)-1)
+	natural := append([]int(nil), widths...)
+	total := 0
+	for _, width := range widths {
+		total += width
+	}
+	for total > available {
+		widest := -1
+		for i, width := range widths {
+			if width <= min(natural[i], colMinWidth) {
+				continue
+			}
+			if widest == -1 || width > widths[widest] {
+				widest = i
+			}
+		}
+		if widest == -1 {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+
+	return widths
+}
+
+func writeTableRow(b *strings.Builder, widths []int, values []string) {
+	for i, width := range widths {
+		var value string
+		if i < len(values) {
+			value = truncate(values[i], width)
+		}
+		b.WriteString(value)
+
+		// For all but the final column, pad to the column width and between
+		// columns
+		if i < len(widths)-1 {
+			b.WriteString(strings.Repeat(" ", width-displayWidth(value)))
+			b.WriteString(colPadding)
+		}
+	}
+
+	// Finish the row with a newline
+	b.WriteString("\n")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Display Width
+
+// truncate shortens `s` to at most `width` terminal cells, marking the
+// truncation with an ellipsis
+//
+// Truncation only ever occurs on grapheme cluster boundaries, so a base
+// character is never separated from its combining marks (or an emoji from its
+// modifiers).
+func truncate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+
+	// Leave room for the marker if we can
+	marker := truncateMarker
+	if width < len(marker) {
+		marker = ""
+	}
+	budget := width - len(marker)
+
+	var b strings.Builder
+	used := 0
+	for cluster := range graphemes(s) {
+		w := clusterWidth(cluster)
+		if used+w > budget {
+			break
+		}
+		b.WriteString(cluster)
+		used += w
+	}
+
+	return b.String() + marker
+}
+
+// displayWidth produces the number of terminal cells occupied by `s`
+func displayWidth(s string) int {
+	width := 0
+	for cluster := range graphemes(s) {
+		width += clusterWidth(cluster)
+	}
+	return width
+}
+
+// graphemes yields the (approximate) grapheme clusters of `s`
+//
+// A cluster is a base rune followed by any combining marks, variation
+// selectors, emoji modifiers and zero-width-joined runes, or a pair of regional
+// indicators (a flag).
+func graphemes(s string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for len(s) > 0 {
+			r, size := utf8.DecodeRuneInString(s)
+			end := size
+			joined := false
+		extend:
+			for end < len(s) {
+				next, nextSize := utf8.DecodeRuneInString(s[end:])
+				switch {
+				case joined, isExtender(next):
+					// Consume the joined rune or the extender
+				case isRegionalIndicator(r) && isRegionalIndicator(next) && end == size:
+					// Flags are pairs of regional indicators
+				default:
+					break extend
+				}
+				joined = next == zeroWidthJoiner
+				end += nextSize
+			}
+
+			if !yield(s[:end]) {
+				return
+			}
+			s = s[end:]
+		}
+	}
+}
+
+const (
+	zeroWidthJoiner    = '\u200d'
+	variationSelector  = '\ufe0f'
+	regionalIndicatorA = '\U0001f1e6'
+	regionalIndicatorZ = '\U0001f1ff'
+)
+
+// isExtender reports whether `r` extends the preceding grapheme cluster
+func isExtender(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoiner ||
+		(r >= '\ufe00' && r <= '\ufe0f') || // Variation selectors
+		(r >= '\U000e0100' && r <= '\U000e01ef') || // Variation selectors supplement
+		(r >= '\U0001f3fb' && r <= '\U0001f3ff') || // Emoji skin tone modifiers
+		(r >= '\U000e0020' && r <= '\U000e007f') // Tags (subdivision flags)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorA && r <= regionalIndicatorZ
+}
+
+// clusterWidth produces the number of terminal cells occupied by grapheme
+// cluster `cluster`
+func clusterWidth(cluster string) int {
+	r, _ := utf8.DecodeRuneInString(cluster)
+	width := runeWidth(r)
+
+	// Flags and text-default symbols requesting emoji presentation render wide
+	if isRegionalIndicator(r) || (width == 1 && strings.ContainsRune(cluster, variationSelector)) {
+		return 2
+	}
+
+	return width
+}
+
+// runeWidth produces the number of terminal cells occupied by rune `r` on its
+// own
+func runeWidth(r rune) int {
+	switch {
+	case r == 0:
+		return 0
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		// Control characters
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		// Combining marks and format characters (ZWJ, etc.)
+		return 0
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// wideRanges are the East Asian Wide and Fullwidth ranges, plus the emoji
+// blocks which terminals render at double width
+var wideRanges = [...][2]rune{
+	{0x1100, 0x115f},   // Hangul Jamo
+	{0x231a, 0x231b},   // Watch, hourglass
+	{0x2329, 0x232a},   // Angle brackets
+	{0x23e9, 0x23ec},   // Media controls
+	{0x23f0, 0x23f0},   // Alarm clock
+	{0x23f3, 0x23f3},   // Hourglass
+	{0x25fd, 0x25fe},   // Squares
+	{0x2614, 0x2615},   // Umbrella, hot beverage
+	{0x2648, 0x2653},   // Zodiac
+	{0x267f, 0x267f},   // Wheelchair
+	{0x2693, 0x2693},   // Anchor
+	{0x26a1, 0x26a1},   // High voltage
+	{0x26aa, 0x26ab},   // Circles
+	{0x26bd, 0x26be},   // Soccer, baseball
+	{0x26c4, 0x26c5},   // Snowman, sun
+	{0x26ce, 0x26ce},   // Ophiuchus
+	{0x26d4, 0x26d4},   // No entry
+	{0x26ea, 0x26ea},   // Church
+	{0x26f2, 0x26f3},   // Fountain, golf
+	{0x26f5, 0x26f5},   // Sailboat
+	{0x26fa, 0x26fa},   // Tent
+	{0x26fd, 0x26fd},   // Fuel pump
+	{0x2705, 0x2705},   // Check mark
+	{0x270a, 0x270b},   // Fists
+	{0x2728, 0x2728},   // Sparkles
+	{0x274c, 0x274c},   // Cross mark
+	{0x274e, 0x274e},   // Cross mark
+	{0x2753, 0x2755},   // Question marks
+	{0x2757, 0x2757},   // Exclamation mark
+	{0x2795, 0x2797},   // Math symbols
+	{0x27b0, 0x27b0},   // Curly loop
+	{0x27bf, 0x27bf},   // Double curly loop
+	{0x2b1b, 0x2b1c},   // Squares
+	{0x2b50, 0x2b50},   // Star
+	{0x2b55, 0x2b55},   // Circle
+	{0x2e80, 0x303e},   // CJK radicals, Kangxi, CJK symbols and punctuation
+	{0x3041, 0x33ff},   // Hiragana, Katakana, Bopomofo, Hangul compat, CJK compat
+	{0x3400, 0x4dbf},   // CJK extension A
+	{0x4e00, 0x9fff},   // CJK unified ideographs
+	{0xa000, 0xa4cf},   // Yi
+	{0xa960, 0xa97f},   // Hangul Jamo extended A
+	{0xac00, 0xd7a3},   // Hangul syllables
+	{0xf900, 0xfaff},   // CJK compatibility ideographs
+	{0xfe10, 0xfe19},   // Vertical forms
+	{0xfe30, 0xfe6f},   // CJK compatibility forms, small forms
+	{0xff00, 0xff60},   // Fullwidth forms
+	{0xffe0, 0xffe6},   // Fullwidth signs
+	{0x16fe0, 0x16fe4}, // Ideographic symbols
+	{0x17000, 0x18cff}, // Tangut
+	{0x1b000, 0x1b2ff}, // Kana supplement/extended, Nushu
+	{0x1f004, 0x1f004}, // Mahjong tile
+	{0x1f0cf, 0x1f0cf}, // Playing card
+	{0x1f18e, 0x1f18e}, // AB button
+	{0x1f191, 0x1f19a}, // Squared words
+	{0x1f200, 0x1f251}, // Enclosed ideographic supplement
+	{0x1f300, 0x1f64f}, // Misc symbols and pictographs, emoticons
+	{0x1f680, 0x1f6ff}, // Transport and map symbols
+	{0x1f7e0, 0x1f7eb}, // Colored circles and squares
+	{0x1f90c, 0x1f9ff}, // Supplemental symbols and pictographs
+	{0x1fa70, 0x1faff}, // Symbols and pictographs extended A
+	{0x20000, 0x2fffd}, // CJK extension B-F
+	{0x30000, 0x3fffd}, // CJK extension G+
+}
+
+func isWide(r rune) bool {
+	// Binary search the (sorted) wide ranges
+	lo, hi := 0, len(wideRanges)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < wideRanges[mid][0]:
+			hi = mid - 1
+		case r > wideRanges[mid][1]:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Terminal
+
+// isTerminal reports whether `f` refers to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth produces the width (in cells) of the terminal attached to `f`
+//
+// `$COLUMNS` takes precedence, falling back to asking the terminal itself and
+// finally to 80 columns.
+func terminalWidth(f *os.File) int {
+	if v, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && v > 0 {
+		return v
+	}
+	if width, ok := terminalSize(f); ok && width > 0 {
+		return width
+	}
+	return 80
+}
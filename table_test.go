@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/illbjorn/zest"
+)
+
+// zwj is a ZWJ sequence (woman + ZWJ + laptop) rendered as a single emoji
+const zwj = "\U0001f469\u200d\U0001f4bb"
+
+func TestDisplayWidth(t *testing.T) {
+	z := zest.New(t)
+
+	testDisplayWidth(z, "errorlens", 9)
+	testDisplayWidth(z, "Café", 4)
+	testDisplayWidth(z, "Cafe\u0301", 4)           // Combining acute accent
+	testDisplayWidth(z, "日本語", 6)                  // CJK
+	testDisplayWidth(z, "한국어", 6)                  // Hangul
+	testDisplayWidth(z, "Ｆｕｌｌ", 8)                 // Fullwidth forms
+	testDisplayWidth(z, "🚀 Rocket", 9)             // Emoji
+	testDisplayWidth(z, "\U0001f44d\U0001f3fd", 2) // Emoji + skin tone modifier
+	testDisplayWidth(z, zwj, 2)                    // ZWJ sequence
+	testDisplayWidth(z, "\U0001f1ef\U0001f1f5", 2) // Flag
+	testDisplayWidth(z, "\u2764\ufe0f", 2)         // Text symbol w/ emoji presentation
+	testDisplayWidth(z, "Python — Microsoft", 18)  // Em dash
+}
+
+func testDisplayWidth(z zest.Zester, input string, want int) {
+	got := displayWidth(input)
+	z.Assert(got == want, "[%s]: expected width [%d], got [%d]", input, want, got)
+}
+
+func TestTruncate(t *testing.T) {
+	z := zest.New(t)
+
+	// Values that fit are untouched
+	testTruncate(z, "errorlens", 9, "errorlens")
+
+	// ASCII
+	testTruncate(z, "errorlens", 8, "error...")
+
+	// Wide characters never split a cell
+	testTruncate(z, "日本語テキスト", 8, "日本...")
+	testTruncate(z, "日本語テキスト", 9, "日本語...")
+
+	// Combining marks stay with their base character
+	testTruncate(z, "Cafe\u0301 Latte", 7, "Cafe\u0301...")
+
+	// ZWJ sequences are never split
+	testTruncate(z, strings.Repeat(zwj, 3), 5, zwj+"...")
+
+	// Tiny widths drop the marker
+	testTruncate(z, "errorlens", 2, "er")
+}
+
+func testTruncate(z zest.Zester, input string, width int, want string) {
+	got := truncate(input, width)
+	z.Assert(got == want, "[%s] @ [%d]: expected [%s], got [%s]", input, width, want, got)
+	z.Assert(displayWidth(got) <= width, "[%s] @ [%d]: result [%s] exceeds width", input, width, got)
+}
+
+func TestTableRender(t *testing.T) {
+	z := zest.New(t)
+
+	table := Table{
+		Columns: []string{"Name", "Publisher"},
+		Rows: [][]string{
+			{"日本語", "Microsoft"},
+			{"Error Lens", "Alexander"},
+		},
+	}
+
+	var b strings.Builder
+	err := table.Render(&b)
+	z.Assert(err == nil, "expected no error, got [%s]", err)
+
+	// Every row's second column must start at the same display offset
+	for line := range strings.Lines(b.String()) {
+		offset := strings.Index(line, "  ") + len(colPadding)
+		for offset < len(line) && line[offset] == ' ' {
+			offset++
+		}
+		got := displayWidth(line[:offset])
+		z.Assert(got == 12, "expected second column at [12], got [%d] in [%s]", got, line)
+	}
+
+	// Fitting into a narrow width shrinks the widest column
+	table.MaxWidth = 20
+	b.Reset()
+	_ = table.Render(&b)
+	for line := range strings.Lines(b.String()) {
+		got := displayWidth(strings.TrimSuffix(line, "\n"))
+		z.Assert(got <= 20, "expected line width <= [20], got [%d] in [%s]", got, line)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var (
+	ErrRawUnsupported = fmt.Errorf("raw terminal mode is unsupported on this platform")
+)
+
+// terminalSize is unsupported on this platform, callers fall back to a default
+// width
+func terminalSize(f *os.File) (int, bool) {
+	return 0, false
+}
+
+// makeRaw is unsupported on this platform, callers fall back to unedited line
+// input
+func makeRaw(f *os.File) (restore func(), err error) {
+	return nil, ErrRawUnsupported
+}
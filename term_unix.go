@@ -0,0 +1,64 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalSize asks the terminal attached to `f` for its width
+func terminalSize(f *os.File) (int, bool) {
+	var ws struct {
+		Row, Col, XPixel, YPixel uint16
+	}
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		f.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&ws)),
+	)
+	if errno != 0 {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
+
+// makeRaw puts the terminal attached to `f` into raw mode, returning a closure
+// which restores its previous state
+//
+// Output post-processing is left enabled so `\n` still produces a newline.
+func makeRaw(f *os.File) (restore func(), err error) {
+	var prev syscall.Termios
+	if err := termios(f, ioctlGetTermios, &prev); err != nil {
+		return nil, err
+	}
+
+	raw := prev
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.Cflag |= syscall.CS8
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN | syscall.ISIG
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termios(f, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = termios(f, ioctlSetTermios, &prev)
+	}, nil
+}
+
+func termios(f *os.File, req uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		f.Fd(),
+		req,
+		uintptr(unsafe.Pointer(t)),
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
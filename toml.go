@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// decodeTOML decodes the subset of TOML used by project configuration files
+// into a generic map, suitable for re-encoding as JSON
+//
+// Supported: comments, bare keys, basic and literal strings, integers, floats,
+// booleans, (multi-line) arrays of those, `[table]` headers and
+// `[[array.of.tables]]` headers. Dotted keys, inline tables, multi-line strings
+// and dates are not.
+func decodeTOML(data []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	current := root
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		// Array of tables
+		if strings.HasPrefix(line, "[[") {
+			name, ok := strings.CutSuffix(line[2:], "]]")
+			if !ok || !isBareKey(strings.TrimSpace(name)) {
+				return nil, fmt.Errorf("line %d: invalid array of tables header [%s]", lineNo, line)
+			}
+			name = strings.TrimSpace(name)
+			tables, _ := root[name].([]any)
+			if _, exists := root[name]; exists && tables == nil {
+				return nil, fmt.Errorf("line %d: [%s] is already defined", lineNo, name)
+			}
+			current = make(map[string]any)
+			root[name] = append(tables, current)
+			continue
+		}
+
+		// Table
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutSuffix(line[1:], "]")
+			if !ok || !isBareKey(strings.TrimSpace(name)) {
+				return nil, fmt.Errorf("line %d: invalid table header [%s]", lineNo, line)
+			}
+			name = strings.TrimSpace(name)
+			if _, exists := root[name]; exists {
+				return nil, fmt.Errorf("line %d: [%s] is already defined", lineNo, name)
+			}
+			current = make(map[string]any)
+			root[name] = current
+			continue
+		}
+
+		// Key/value pair
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isBareKey(key) {
+			return nil, fmt.Errorf("line %d: expected `key = value`", lineNo)
+		}
+		if _, exists := current[key]; exists {
+			return nil, fmt.Errorf("line %d: key [%s] is already defined", lineNo, key)
+		}
+		value = strings.TrimSpace(value)
+
+		// Arrays may span multiple lines, gather until the brackets balance
+		for strings.HasPrefix(value, "[") && !tomlBalanced(value) {
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("line %d: unterminated array", lineNo)
+			}
+			lineNo++
+			value += " " + strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		}
+
+		v, rest, err := parseTOMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("line %d: unexpected trailing [%s]", lineNo, rest)
+		}
+		current[key] = v
+	}
+
+	return root, scanner.Err()
+}
+
+// parseTOMLValue parses a single value from the start of `s`, returning the
+// value and the remainder of `s`
+func parseTOMLValue(s string) (any, string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, "", fmt.Errorf("missing value")
+	}
+
+	switch s[0] {
+	case '"':
+		// Find the closing quote, skipping escaped characters
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				v, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid string %s: %w", s[:i+1], err)
+				}
+				return v, s[i+1:], nil
+			}
+		}
+		return nil, "", fmt.Errorf("unterminated string")
+
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end == -1 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+
+	case '[':
+		var values []any
+		s = strings.TrimSpace(s[1:])
+		for {
+			if strings.HasPrefix(s, "]") {
+				return values, s[1:], nil
+			}
+			v, rest, err := parseTOMLValue(s)
+			if err != nil {
+				return nil, "", err
+			}
+			values = append(values, v)
+			s = strings.TrimSpace(rest)
+			if after, ok := strings.CutPrefix(s, ","); ok {
+				s = strings.TrimSpace(after)
+			} else if !strings.HasPrefix(s, "]") {
+				return nil, "", fmt.Errorf("expected `,` or `]` in array")
+			}
+		}
+	}
+
+	// Bare values run until the next delimiter
+	end := strings.IndexAny(s, ",] \t")
+	if end == -1 {
+		end = len(s)
+	}
+	token, rest := s[:end], s[end:]
+
+	switch token {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	if i, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 0, 64); err == nil {
+		return i, rest, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(token, "_", ""), 64); err == nil {
+		return f, rest, nil
+	}
+
+	return nil, "", fmt.Errorf("unsupported value [%s]", token)
+}
+
+// stripTOMLComment removes a trailing `#` comment from `line`, ignoring `#`
+// within strings
+func stripTOMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlBalanced reports whether every `[` in `s` (outside of strings) has been
+// closed
+func tomlBalanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '[':
+			depth++
+		case quote == 0 && c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+func isBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
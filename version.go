@@ -0,0 +1,263 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSemver     = fmt.Errorf("invalid semantic version")
+	ErrConstraint = fmt.Errorf("invalid version constraint")
+)
+
+// compareVersions compares dot-separated version strings `a` and `b`,
+// returning -1 if `a` precedes `b`, 1 if `a` follows `b` and 0 if they're
+// equal.
+//
+// Semantic versions compare as such, ordering pre-releases before their
+// release. Otherwise, numeric segments compare numerically and anything else
+// compares lexically.
+func compareVersions(a, b string) int {
+	if as, err := ParseSemver(a); err == nil {
+		if bs, err := ParseSemver(b); err == nil {
+			if c := as.Compare(bs); c != 0 {
+				return c
+			}
+		}
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := range max(len(as), len(bs)) {
+		// Missing segments are considered zero (`1.2` == `1.2.0`)
+		aSeg, bSeg := "0", "0"
+		if i < len(as) {
+			aSeg = as[i]
+		}
+		if i < len(bs) {
+			bSeg = bs[i]
+		}
+
+		aNum, aErr := strconv.Atoi(aSeg)
+		bNum, bErr := strconv.Atoi(bSeg)
+		if aErr == nil && bErr == nil {
+			if aNum != bNum {
+				return cmp.Compare(aNum, bNum)
+			}
+			continue
+		}
+
+		if c := strings.Compare(aSeg, bSeg); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Semantic Versions
+
+// Semver is a semantic version (https://semver.org), as used by extension and
+// editor versions
+type Semver struct {
+	Major int
+	Minor int
+	Patch int
+
+	// Pre is the dot-separated pre-release suffix, without its `-`
+	Pre string
+
+	// parts is the number of version parts present when parsed (ex: 2 for
+	// `1.4`), used by constraints treating missing parts as wildcards
+	parts int
+}
+
+// ParseSemver parses version `s` (ex: `1.4.2`, `v2.0.0-beta.1`)
+//
+// Missing minor or patch versions are considered zero, and build metadata
+// (`+...`) is ignored.
+func ParseSemver(s string) (Semver, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	v, _, _ = strings.Cut(v, "+")
+
+	var self Semver
+	v, self.Pre, _ = strings.Cut(v, "-")
+
+	nums := strings.Split(v, ".")
+	if len(nums) > 3 {
+		return Semver{}, fmt.Errorf("%w [%s]", ErrSemver, s)
+	}
+	for i, num := range nums {
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 0 {
+			return Semver{}, fmt.Errorf("%w [%s]", ErrSemver, s)
+		}
+		switch i {
+		case 0:
+			self.Major = n
+		case 1:
+			self.Minor = n
+		case 2:
+			self.Patch = n
+		}
+	}
+	self.parts = len(nums)
+
+	return self, nil
+}
+
+func (self Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", self.Major, self.Minor, self.Patch)
+	if self.Pre != "" {
+		s += "-" + self.Pre
+	}
+	return s
+}
+
+// Compare returns -1 if `self` precedes `other`, 1 if it follows and 0 if
+// they're equal, ordering pre-releases before their release
+func (self Semver) Compare(other Semver) int {
+	if c := cmp.Compare(self.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(self.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(self.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	switch {
+	case self.Pre == other.Pre:
+		return 0
+	case self.Pre == "":
+		return 1
+	case other.Pre == "":
+		return -1
+	}
+
+	// Pre-release identifiers compare numerically where both are numeric,
+	// numeric identifiers preceding the rest
+	as, bs := strings.Split(self.Pre, "."), strings.Split(other.Pre, ".")
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Constraints
+
+// Constraint is a set of version comparisons, all of which must hold (ex:
+// `>=1.0 <2.0`)
+type Constraint []comparison
+
+type comparison struct {
+	op string
+	v  Semver
+}
+
+// ParseConstraint parses version constraint `s`, a space-separated list of
+// comparisons, each one of:
+//
+//	^1.4     compatible with 1.4 (>=1.4.0 <2.0.0; for 0.x, <0.(x+1).0)
+//	~2.3.1   patch updates of 2.3.1 (>=2.3.1 <2.4.0)
+//	>=1.0    >1.0  <=1.0  <1.0  =1.0
+//	1.4      any 1.4.x (a complete version matches exactly)
+//	*        any version
+func ParseConstraint(s string) (Constraint, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrConstraint)
+	}
+
+	var self Constraint
+	for _, field := range fields {
+		if field == "*" || field == "x" {
+			continue
+		}
+
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if rest, ok := strings.CutPrefix(field, prefix); ok {
+				op, field = prefix, rest
+				break
+			}
+		}
+		v, err := ParseSemver(strings.TrimSuffix(strings.TrimSuffix(field, ".x"), ".*"))
+		if err != nil {
+			return nil, fmt.Errorf("%w [%s]: %w", ErrConstraint, s, err)
+		}
+
+		switch op {
+		case "^":
+			upper := Semver{Major: v.Major + 1}
+			switch {
+			case v.Major == 0 && v.parts >= 2 && v.Minor > 0:
+				upper = Semver{Minor: v.Minor + 1}
+			case v.Major == 0 && v.parts == 3:
+				upper = Semver{Minor: v.Minor, Patch: v.Patch + 1}
+			case v.Major == 0 && v.parts == 2:
+				upper = Semver{Minor: 1}
+			}
+			self = append(self, comparison{">=", v}, comparison{"<", upper})
+		case "~", "":
+			if op == "" && v.parts == 3 {
+				self = append(self, comparison{"=", v})
+				continue
+			}
+			upper := Semver{Major: v.Major, Minor: v.Minor + 1}
+			if v.parts == 1 {
+				upper = Semver{Major: v.Major + 1}
+			}
+			self = append(self, comparison{">=", v}, comparison{"<", upper})
+		default:
+			self = append(self, comparison{op, v})
+		}
+	}
+
+	return self, nil
+}
+
+// Match reports whether version `v` satisfies every comparison
+func (self Constraint) Match(v Semver) bool {
+	for _, cmp := range self {
+		c := v.Compare(cmp.v)
+		var ok bool
+		switch cmp.op {
+		case ">=":
+			ok = c >= 0
+		case ">":
+			ok = c > 0
+		case "<=":
+			ok = c <= 0
+		case "<":
+			ok = c < 0
+		case "=":
+			ok = c == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
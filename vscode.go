@@ -2,40 +2,8 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-
-	"github.com/illbjorn/echo"
 )
 
-func ExtensionDir() (string, error) {
-	// Get the home directory path
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get user home directory: %w", err)
-	}
-
-	// Look for a `.vscode-oss` or `.vscode` directory in the user's home path
-	var extDir string
-	extDirOSS := filepath.Join(home, ".vscode-oss")
-	extDirMS := filepath.Join(home, ".vscode")
-	if _, err := os.Lstat(extDirOSS); err == nil {
-		extDir = filepath.Join(extDirOSS, "extensions")
-		echo.Debugf("Using extension directory [%s].", extDirOSS)
-
-	} else if _, err := os.Lstat(extDirMS); err == nil {
-		extDir = filepath.Join(extDirMS, "extensions")
-		echo.Debugf("Using extension directory [%s].", extDirMS)
-
-	} else {
-		echo.Debugf("Attempted: %s.", extDirOSS)
-		echo.Debugf("Attempted: %s.", extDirMS)
-		echo.Fatal("An extension directory was not provided and we failed to locate one.")
-	}
-
-	return extDir, nil
-}
-
 var (
 	ErrNoDot = fmt.Errorf("extension input missing dot separator ('publisher.ID')")
 	ErrNoPub = fmt.Errorf("extension input missing publisher ('publisher.ID')")
@@ -96,6 +64,11 @@ func ParseExtension(input string) (extPub, extID, extVer string, err error) {
 		}
 		extID = input[dot+1 : at]
 		extVer = input[at+1:]
+
+		// The version must be a valid version specifier (ex: `^1.4`)
+		if _, err = ParseVersionSpec(extVer); err != nil {
+			return
+		}
 	}
 
 	return
@@ -24,6 +24,16 @@ func TestParseExtensionInput(t *testing.T) {
 	testParseExtensionInput(z, "modular-mojotools.vscode-mojo", "modular-mojotools", "vscode-mojo", "", nil)
 	testParseExtensionInput(z, "modular-mojotools.vscode-mojo@3.25", "modular-mojotools", "vscode-mojo", "3.25", nil)
 
+	// version specifiers
+	testParseExtensionInput(z, "usernamehw.errorlens@^1.4", "usernamehw", "errorlens", "^1.4", nil)
+	testParseExtensionInput(z, "usernamehw.errorlens@~2.3.1", "usernamehw", "errorlens", "~2.3.1", nil)
+	testParseExtensionInput(z, "usernamehw.errorlens@>=1.0 <2.0", "usernamehw", "errorlens", ">=1.0 <2.0", nil)
+	testParseExtensionInput(z, "usernamehw.errorlens@prerelease", "usernamehw", "errorlens", "prerelease", nil)
+	testParseExtensionInput(z, "usernamehw.errorlens@latest-stable", "usernamehw", "errorlens", "latest-stable", nil)
+	testParseExtensionInput(z, "usernamehw.errorlens@latest", "usernamehw", "errorlens", "latest", nil)
+	testParseExtensionInput(z, "usernamehw.errorlens@newest", "", "", "", ErrVersionSpec)
+	testParseExtensionInput(z, "usernamehw.errorlens@>=one", "", "", "", ErrVersionSpec)
+
 	// ill-formed version
 	testParseExtensionInput(z, "modular-mojotools.vscode-mojo@", "modular-mojotools", "vscode-mojo", "3.25", ErrAtOOB)
 	testParseExtensionInput(z, "@modular-mojotools.vscode-mojo", "modular-mojotools", "vscode-mojo", "3.25", ErrAtOOB)
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/illbjorn/argv"
+	"github.com/illbjorn/vsx/gallery"
+)
+
+const (
+	// vsixManifestName is the VSIX package manifest's path within the package
+	vsixManifestName = "extension.vsixmanifest"
+
+	// defaultNameTemplate is the default `--name-template`
+	defaultNameTemplate = "{publisher}.{name}-{version}.vsix"
+
+	// platformUniversal is the target platform of extensions published for
+	// every platform
+	platformUniversal = "universal"
+)
+
+var (
+	ErrVSIXManifest = fmt.Errorf("failed to read the VSIX package manifest")
+	ErrNameTemplate = fmt.Errorf("invalid name template")
+)
+
+// VSIXIdentity identifies the extension held by a VSIX package
+type VSIXIdentity struct {
+	Publisher      string `xml:"Publisher,attr"`
+	Name           string `xml:"Id,attr"`
+	Version        string `xml:"Version,attr"`
+	TargetPlatform string `xml:"TargetPlatform,attr"`
+}
+
+// vsixManifest is the subset of the VSIX package manifest read
+type vsixManifest struct {
+	Identity VSIXIdentity `xml:"Metadata>Identity"`
+
+	// Assets locate the package's assets (ex: its license) within it
+	Assets []struct {
+		Type string `xml:"Type,attr"`
+		Path string `xml:"Path,attr"`
+	} `xml:"Assets>Asset"`
+}
+
+// readVSIXManifest reads the manifest of VSIX package `zr`
+func readVSIXManifest(zr *zip.Reader) (*vsixManifest, error) {
+	f, err := zr.Open(vsixManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVSIXManifest, err)
+	}
+	defer f.Close()
+
+	manifest := new(vsixManifest)
+	if err := xml.NewDecoder(f).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrVSIXManifest, err)
+	}
+	if manifest.Identity.Version == "" {
+		return nil, fmt.Errorf("%w: no identity version", ErrVSIXManifest)
+	}
+
+	return manifest, nil
+}
+
+// readVSIXIdentity reads the identity of the extension in VSIX package `zr`
+// from its manifest
+func readVSIXIdentity(zr *zip.Reader) (VSIXIdentity, error) {
+	manifest, err := readVSIXManifest(zr)
+	if err != nil {
+		return VSIXIdentity{}, err
+	}
+	return manifest.Identity, nil
+}
+
+// ParseNameTemplate produces the file name template requested by `cmd`,
+// defaulting to defaultNameTemplate
+func ParseNameTemplate(cmd argv.Command) (string, error) {
+	v, ok := cmd.Flag(flagNameTemplate)
+	if !ok {
+		return defaultNameTemplate, nil
+	}
+
+	// Render a sample to catch errors before downloading anything
+	sample := VSIXIdentity{Publisher: "pub", Name: "name", Version: "1.0.0"}
+	if _, err := renderName(v[0], sample); err != nil {
+		return "", fmt.Errorf("--%s: %w", flagNameTemplate, err)
+	}
+	return v[0], nil
+}
+
+// renderName renders file name template `tmpl` for the extension identified by
+// `id`
+//
+// The placeholders `{publisher}`, `{name}`, `{version}` and `{platform}` are
+// supported, `{platform}` being `universal` for extensions published for every
+// platform.
+func renderName(tmpl string, id VSIXIdentity) (string, error) {
+	platform := id.TargetPlatform
+	if platform == "" {
+		platform = platformUniversal
+	}
+
+	var b strings.Builder
+	for rest := tmpl; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open == -1 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:open])
+
+		end := strings.IndexByte(rest[open:], '}')
+		if end == -1 {
+			return "", fmt.Errorf("%w [%s]: unterminated placeholder", ErrNameTemplate, tmpl)
+		}
+		switch placeholder := rest[open+1 : open+end]; placeholder {
+		case "publisher":
+			b.WriteString(id.Publisher)
+		case "name":
+			b.WriteString(id.Name)
+		case "version":
+			b.WriteString(id.Version)
+		case "platform":
+			b.WriteString(platform)
+		default:
+			return "", fmt.Errorf("%w [%s]: unknown placeholder {%s}", ErrNameTemplate, tmpl, placeholder)
+		}
+		rest = rest[open+end+1:]
+	}
+
+	// Names may not escape the output directory
+	name := b.String()
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w [%s]: [%s] is not a local file name", ErrNameTemplate, tmpl, name)
+	}
+
+	return name, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Package Contents
+
+const (
+	// vsixContentTypesName is the path of the package's content type map
+	vsixContentTypesName = "[Content_Types].xml"
+
+	// vsixPackageJSONName is the path of the extension's `package.json`
+	vsixPackageJSONName = "extension/package.json"
+
+	// nativeSniffLen is the number of leading bytes read from each file to
+	// detect native binaries
+	nativeSniffLen = 512
+
+	// maxLicenseSize is the number of leading bytes of license files read
+	maxLicenseSize = 1 << 20
+
+	// licenseSeeIn prefixes license fields naming a license file rather than a
+	// license (ex: `SEE LICENSE IN LICENSE.txt`)
+	licenseSeeIn = "SEE LICENSE IN "
+)
+
+// Native binary formats
+const (
+	nativeELF   = "ELF"
+	nativeMachO = "Mach-O"
+	nativePE    = "PE"
+)
+
+// VSIXPackage is the parsed content of a VSIX package
+type VSIXPackage struct {
+	Identity VSIXIdentity
+
+	// ContentTypes maps file extensions to their declared media types
+	ContentTypes map[string]string
+
+	// Manifest is the extension's `package.json`
+	Manifest ExtensionManifest
+
+	// Assets maps asset types (ex: `Microsoft.VisualStudio.Services.Content.
+	// License`) to the files holding them
+	Assets map[string]string
+
+	Files []VSIXFile
+
+	// LicenseFile is the name of the extension's license file, or the empty
+	// string if it has none, and License its content
+	LicenseFile string
+	License     string
+
+	// SHA256 is the hex-encoded SHA-256 digest of the package file, or the
+	// empty string if unknown (ex: installed extensions)
+	SHA256 string
+
+	// extension holds the content of the package's `extension/` directory,
+	// readable while the package's source remains open
+	extension fs.FS
+}
+
+// VSIXFile is a single file in a VSIX package
+type VSIXFile struct {
+	Name           string `json:"name"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressed_size"`
+
+	// CRC32 is the file's checksum as recorded by the archive, comparing
+	// content without extracting it
+	CRC32 uint32 `json:"-"`
+
+	// Native is the file's native binary format (ex: `ELF`), or the empty
+	// string if not a native binary
+	Native string `json:"native,omitempty"`
+}
+
+// ExtensionManifest is the subset of an extension's `package.json` describing
+// what it does and needs
+type ExtensionManifest struct {
+	Publisher             string            `json:"publisher"`
+	Name                  string            `json:"name"`
+	Version               string            `json:"version"`
+	DisplayName           string            `json:"displayName"`
+	Engines               map[string]string `json:"engines"`
+	ActivationEvents      []string          `json:"activationEvents"`
+	Main                  string            `json:"main"`
+	Browser               string            `json:"browser"`
+	ExtensionDependencies []string          `json:"extensionDependencies"`
+	ExtensionPack         []string          `json:"extensionPack"`
+	Dependencies          map[string]string `json:"dependencies"`
+	License               string            `json:"license"`
+
+	// EnabledAPIProposals are the proposed (unstable) editor APIs used
+	EnabledAPIProposals []string `json:"enabledApiProposals"`
+
+	// Capabilities declare support for restricted modes (ex: untrusted
+	// workspaces)
+	Capabilities map[string]json.RawMessage `json:"capabilities"`
+
+	// Scripts are the package's npm scripts, including lifecycle scripts (ex:
+	// `postinstall`)
+	Scripts map[string]string `json:"scripts"`
+
+	// ExtensionKind lists where the extension prefers to run: `ui` (alongside
+	// the editor) or `workspace` (alongside the workspace, possibly remote)
+	ExtensionKind stringList `json:"extensionKind"`
+
+	// Contributes is kept raw, as contribution points vary in shape
+	Contributes map[string]json.RawMessage `json:"contributes"`
+
+	// Metadata is written by VS Code itself on install
+	Metadata struct {
+		TargetPlatform string `json:"targetPlatform"`
+	} `json:"__metadata,omitzero"`
+}
+
+// stringList is a list of strings which may also be written as a single string
+type stringList []string
+
+func (self *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*self = stringList{s}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(self))
+}
+
+// contributionKey identifies a contribution point (ex: `commands`) and the field
+// identifying each of its contributions (ex: `command`)
+type contributionKey struct {
+	point string
+	key   string
+}
+
+var (
+	contribCommands  = contributionKey{"commands", "command"}
+	contribLanguages = contributionKey{"languages", "id"}
+	contribDebuggers = contributionKey{"debuggers", "type"}
+)
+
+// contributionPoints are the contribution points compared by identifier
+var contributionPoints = map[string]contributionKey{
+	contribCommands.point:  contribCommands,
+	contribLanguages.point: contribLanguages,
+	contribDebuggers.point: contribDebuggers,
+}
+
+// Contributions produces the identifiers of the manifest's contributions to
+// `contrib`'s point, in declaration order
+//
+// Points holding a single object rather than a list are accepted, and entries
+// lacking the identifying key are skipped.
+func (self ExtensionManifest) Contributions(contrib contributionKey) []string {
+	raw, ok := self.Contributes[contrib.point]
+	if !ok {
+		return nil
+	}
+
+	var entries []map[string]any
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		var entry map[string]any
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil
+		}
+		entries = append(entries, entry)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := entry[contrib.key].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ReadVSIX parses the manifests of VSIX package `zr` and catalogs its files,
+// detecting native binaries
+func ReadVSIX(zr *zip.Reader) (*VSIXPackage, error) {
+	vsix, err := readVSIXManifest(zr)
+	if err != nil {
+		return nil, err
+	}
+	self := &VSIXPackage{
+		Identity:     vsix.Identity,
+		ContentTypes: make(map[string]string),
+		Assets:       make(map[string]string),
+	}
+	for _, asset := range vsix.Assets {
+		self.Assets[asset.Type] = asset.Path
+	}
+	if self.extension, err = fs.Sub(zr, "extension"); err != nil {
+		return nil, err
+	}
+
+	// [Content_Types].xml
+	if f, err := zr.Open(vsixContentTypesName); err == nil {
+		var types struct {
+			Defaults []struct {
+				Extension   string `xml:"Extension,attr"`
+				ContentType string `xml:"ContentType,attr"`
+			} `xml:"Default"`
+		}
+		err := xml.NewDecoder(f).Decode(&types)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read [%s]: %w", vsixContentTypesName, err)
+		}
+		for _, d := range types.Defaults {
+			self.ContentTypes[strings.TrimPrefix(d.Extension, ".")] = d.ContentType
+		}
+	}
+
+	// extension/package.json
+	f, err := zr.Open(vsixPackageJSONName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read [%s]: %w", vsixPackageJSONName, err)
+	}
+	err = json.NewDecoder(f).Decode(&self.Manifest)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read [%s]: %w", vsixPackageJSONName, err)
+	}
+
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		file := VSIXFile{
+			Name:           zf.Name,
+			Size:           int64(zf.UncompressedSize64),
+			CompressedSize: int64(zf.CompressedSize64),
+			CRC32:          zf.CRC32,
+		}
+		if file.Native, err = sniffNative(zf); err != nil {
+			return nil, fmt.Errorf("failed to read [%s]: %w", zf.Name, err)
+		}
+		self.Files = append(self.Files, file)
+	}
+
+	if self.LicenseFile = self.licenseFile(); self.LicenseFile != "" {
+		if self.License, err = readZipFile(zr, self.LicenseFile, maxLicenseSize); err != nil {
+			return nil, fmt.Errorf("failed to read [%s]: %w", self.LicenseFile, err)
+		}
+	}
+
+	return self, nil
+}
+
+// native reports whether any of the package's files is a native binary
+func (self *VSIXPackage) native() bool {
+	return slices.ContainsFunc(self.Files, func(f VSIXFile) bool {
+		return f.Native != ""
+	})
+}
+
+// licenseFile produces the name of the package's license file: its license
+// asset, the file named by a `SEE LICENSE IN` license field, or a license file
+// at the extension's root (ex: `LICENSE.md`), in that order
+func (self *VSIXPackage) licenseFile() string {
+	names := make(map[string]bool, len(self.Files))
+	for _, f := range self.Files {
+		names[f.Name] = true
+	}
+
+	if name := self.Assets[string(gallery.AssetLicense)]; names[name] {
+		return name
+	}
+	if file, ok := seeLicenseIn(self.Manifest.License); ok {
+		if name := path.Join("extension", file); names[name] {
+			return name
+		}
+	}
+	for _, f := range self.Files {
+		rest, ok := strings.CutPrefix(f.Name, "extension/")
+		if !ok || strings.Contains(rest, "/") {
+			continue
+		}
+		if isLicenseFile(rest) {
+			return f.Name
+		}
+	}
+	return ""
+}
+
+// seeLicenseIn produces the file named by license field `license` if of the
+// form `SEE LICENSE IN <file>`
+func seeLicenseIn(license string) (string, bool) {
+	license = strings.TrimSpace(license)
+	if len(license) <= len(licenseSeeIn) || !strings.EqualFold(license[:len(licenseSeeIn)], licenseSeeIn) {
+		return "", false
+	}
+	return strings.TrimSpace(license[len(licenseSeeIn):]), true
+}
+
+// readZipFile reads up to `limit` bytes of file `name` in `zr`
+func readZipFile(zr *zip.Reader, name string, limit int64) (string, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, limit))
+	return string(b), err
+}
+
+// sniffNative detects the native binary format of `zf` by its magic number,
+// producing the empty string for anything else
+func sniffNative(zf *zip.File) (string, error) {
+	if zf.UncompressedSize64 < 4 {
+		return "", nil
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return readNative(rc)
+}
+
+// readNative detects the native binary format of the content of `r` by its
+// magic number, producing the empty string for anything else
+func readNative(r io.Reader) (string, error) {
+	head := make([]byte, nativeSniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	head = head[:n]
+	if len(head) < 4 {
+		return "", nil
+	}
+
+	switch magic := binary.BigEndian.Uint32(head); {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return nativeELF, nil
+	case magic == 0xfeedface || magic == 0xfeedfacf || magic == 0xcefaedfe || magic == 0xcffaedfe:
+		return nativeMachO, nil
+	case magic == 0xcafebabe && len(head) >= 8 && binary.BigEndian.Uint32(head[4:]) < 32:
+		// Universal Mach-O binaries share their magic number with Java class
+		// files, which follow it with a version number well above any count
+		// of architectures
+		return nativeMachO, nil
+	case bytes.HasPrefix(head, []byte("MZ")) && len(head) >= 0x40:
+		// The PE signature follows the DOS stub, at the offset at 0x3c
+		offset := uint64(binary.LittleEndian.Uint32(head[0x3c:]))
+		return readPESignature(r, head, offset)
+	}
+	return "", nil
+}
+
+// readPESignature reads the PE signature of a DOS executable at `offset`,
+// from the sniffed bytes `head` and then the rest of it in `r`
+//
+// Executables ending before their signature are treated as PE all the same,
+// rather than let a bogus offset hide them. The offset is bounded before any
+// conversion, which could otherwise overflow `int` on 32-bit platforms.
+func readPESignature(r io.Reader, head []byte, offset uint64) (string, error) {
+	var sig []byte
+	if offset < uint64(len(head)) {
+		sig = slices.Clone(head[offset:min(offset+4, uint64(len(head)))])
+	} else if _, err := io.CopyN(io.Discard, r, int64(offset-uint64(len(head)))); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nativePE, nil
+		}
+		return "", err
+	}
+
+	rest := make([]byte, 4-len(sig))
+	if _, err := io.ReadFull(r, rest); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nativePE, nil
+		}
+		return "", err
+	}
+	sig = append(sig, rest...)
+	if string(sig) == "PE\x00\x00" {
+		return nativePE, nil
+	}
+	return "", nil
+}